@@ -0,0 +1,81 @@
+/*
+* アンカー
+* 本文中の >>N、>>N-M の形式で他の投稿を参照する
+ */
+
+package anchor
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// 1つの本文から参照できる投稿の数の上限(>>1-100000 のような範囲による負荷を防ぐ)
+const MaxTargets = 100
+
+// アンカーの正規表現
+var (
+	pattern       = regexp.MustCompile(`>>([0-9]+)(?:-([0-9]+))?`)
+	prefixPattern = regexp.MustCompile(`^>>([0-9]+)(?:-([0-9]+))?`)
+)
+
+// 本文中のアンカー
+type Match struct {
+	Start int // 本文中の開始位置(バイト単位)
+	End   int // 本文中の終了位置(この位置は含まない)
+	From  int // 参照する投稿IDの範囲の始まり
+	To    int // 参照する投稿IDの範囲の終わり(単独の場合はFromと同じ)
+}
+
+// 本文中のアンカーをすべて探す
+func Find(content string) []Match {
+	matches := []Match{}
+	for _, loc := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		if m, ok := newMatch(content, loc); ok {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// 文字列の先頭のアンカーを探す
+func Prefix(s string) (Match, bool) {
+	loc := prefixPattern.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return Match{}, false
+	}
+	return newMatch(s, loc)
+}
+
+// 正規表現の一致位置からアンカーを作成する
+// 範囲の終わりが始まり以前の場合は始まりだけを参照する
+func newMatch(s string, loc []int) (Match, bool) {
+	from, err := strconv.Atoi(s[loc[2]:loc[3]])
+	if err != nil || from <= 0 {
+		return Match{}, false
+	}
+	m := Match{Start: loc[0], End: loc[1], From: from, To: from}
+	if loc[4] >= 0 {
+		if to, err := strconv.Atoi(s[loc[4]:loc[5]]); err == nil && to > from {
+			m.To = to
+		}
+	}
+	return m, true
+}
+
+// 本文中のアンカーが参照する投稿のIDを、重複を除いて昇順で返す(最大 MaxTargets 件)
+func Parse(content string) []int {
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, m := range Find(content) {
+		for id := m.From; id <= m.To && len(ids) < MaxTargets; id++ {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
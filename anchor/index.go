@@ -0,0 +1,115 @@
+package anchor
+
+import (
+	"sort"
+	"sync"
+)
+
+// アンカーによる参照元(投稿の本文、またはコメント)
+type Source struct {
+	PostID    int // 投稿のID(コメントの場合はコメントが属する投稿)
+	CommentID int // コメントのID(投稿の本文の場合は0)
+}
+
+// 参照元の並び順(投稿ID、同じ投稿の中では投稿の本文、コメントIDの順)
+func (s Source) less(other Source) bool {
+	if s.PostID != other.PostID {
+		return s.PostID < other.PostID
+	}
+	return s.CommentID < other.CommentID
+}
+
+// アンカーによる参照の索引
+// 参照先から参照元を引く逆引き(被参照)も保持する
+// 複数のリクエストから並行して読み書きされるため、ロックで保護する
+type Index struct {
+	mu      sync.RWMutex
+	targets map[Source][]int            // 参照元ごとの参照先の投稿ID(昇順)
+	sources map[int]map[Source]struct{} // 参照先の投稿IDごとの参照元
+}
+
+// 空の索引を作成する
+func NewIndex() *Index {
+	return &Index{
+		targets: map[Source][]int{},
+		sources: map[int]map[Source]struct{}{},
+	}
+}
+
+// 参照元の本文を解析し、参照先を置き換える
+// 投稿の本文から投稿自身への参照は登録しない
+func (ix *Index) Set(source Source, content string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(source)
+	targets := []int{}
+	for _, id := range Parse(content) {
+		if source.CommentID == 0 && id == source.PostID {
+			continue
+		}
+		targets = append(targets, id)
+	}
+	if len(targets) == 0 {
+		return
+	}
+	ix.targets[source] = targets
+	for _, id := range targets {
+		if ix.sources[id] == nil {
+			ix.sources[id] = map[Source]struct{}{}
+		}
+		ix.sources[id][source] = struct{}{}
+	}
+}
+
+// 参照元を削除する
+func (ix *Index) Remove(source Source) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(source)
+}
+
+// 投稿の本文とそのコメントを参照元から削除する
+func (ix *Index) RemovePost(postID int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for source := range ix.targets {
+		if source.PostID == postID {
+			ix.remove(source)
+		}
+	}
+}
+
+// 参照元を削除する(ロックは呼び出し元で取得する)
+func (ix *Index) remove(source Source) {
+	for _, id := range ix.targets[source] {
+		delete(ix.sources[id], source)
+		if len(ix.sources[id]) == 0 {
+			delete(ix.sources, id)
+		}
+	}
+	delete(ix.targets, source)
+}
+
+// 投稿の本文が参照している投稿のIDを昇順で返す
+func (ix *Index) Targets(postID int) []int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return append([]int{}, ix.targets[Source{PostID: postID}]...)
+}
+
+// 投稿を参照している参照元を返す(投稿ID、コメントIDの順)
+func (ix *Index) Sources(postID int) []Source {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	sources := make([]Source, 0, len(ix.sources[postID]))
+	for source := range ix.sources[postID] {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].less(sources[j]) })
+	return sources
+}
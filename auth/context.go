@@ -0,0 +1,36 @@
+/*
+* 認証
+* リクエストのコンテキストに認証済みユーザーを保持する
+ */
+
+package auth
+
+import (
+	"bbs-gql-project/models"
+	"context"
+)
+
+// コンテキストのキー
+type contextKey struct{}
+
+// 認証済みユーザーをコンテキストに設定する
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// コンテキストから認証済みユーザーを取得する
+// 認証されていない場合は nil を返す
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(contextKey{}).(*models.User)
+	return user
+}
+
+// コンテキストから認証済みユーザーを取得する
+// 認証されていない場合は UNAUTHENTICATED エラーを返す
+func RequireUser(ctx context.Context) (*models.User, error) {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return nil, TokenMissingError()
+	}
+	return user, nil
+}
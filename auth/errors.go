@@ -0,0 +1,38 @@
+package auth
+
+import "bbs-gql-project/models"
+
+// 認証エラーの理由(extensions の reason に出力する)
+const (
+	ReasonTokenMissing   = "TOKEN_MISSING"
+	ReasonTokenMalformed = "TOKEN_MALFORMED"
+	ReasonTokenExpired   = "TOKEN_EXPIRED"
+	ReasonTokenInvalid   = "TOKEN_INVALID"
+)
+
+// 理由を付けた UNAUTHENTICATED エラーを作成する
+func unauthenticated(reason string, message string, detail string) *models.AppError {
+	err := models.UnauthenticatedError(message, detail)
+	err.Extensions = map[string]interface{}{"reason": reason}
+	return err
+}
+
+// トークンが指定されていない
+func TokenMissingError() *models.AppError {
+	return unauthenticated(ReasonTokenMissing, "authentication required", "no access token was provided")
+}
+
+// トークンの形式が正しくない
+func TokenMalformedError(detail string) *models.AppError {
+	return unauthenticated(ReasonTokenMalformed, "malformed access token", detail)
+}
+
+// トークンの有効期限が切れている
+func TokenExpiredError() *models.AppError {
+	return unauthenticated(ReasonTokenExpired, "access token has expired", "access token has expired")
+}
+
+// トークンの署名や内容が正しくない
+func TokenInvalidError(detail string) *models.AppError {
+	return unauthenticated(ReasonTokenInvalid, "invalid access token", detail)
+}
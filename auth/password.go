@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 存在しないユーザーでのログイン時に照合するハッシュ
+// 照合を省くと応答時間の差から登録済みのユーザー名を推測できるため、同じコストで計算させる
+const dummyPasswordHash = "$2a$10$QglCHRr/kcOOOGhCYYjkGOdnZnbXdr3gxtwD/FvnZjdcoZC3bfmJC"
+
+// パスワードをbcryptでハッシュ化する
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// パスワードがハッシュと一致するかを確認する
+func CheckPassword(hash string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 存在しないユーザーに対して、実在するユーザーと同じ時間をかけてパスワードを照合する(結果は常に不一致)
+func CheckDummyPassword(password string) {
+	CheckPassword(dummyPasswordHash, password)
+}
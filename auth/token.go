@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"bbs-gql-project/models"
+	"crypto/ed25519"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// トークンの発行者
+const issuer = "bbs-gql-project"
+
+// JWTの発行と検証を行う
+type Tokens struct {
+	method    jwt.SigningMethod // 署名アルゴリズム
+	signKey   interface{}       // 署名鍵
+	verifyKey interface{}       // 検証鍵
+	ttl       time.Duration     // トークンの有効期間
+}
+
+// HS256で署名・検証するトークン発行者を作成する
+func NewTokens(secret []byte, ttl time.Duration) *Tokens {
+	return &Tokens{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret, ttl: ttl}
+}
+
+// EdDSA(Ed25519)で署名・検証するトークン発行者を作成する
+func NewEdDSATokens(key ed25519.PrivateKey, ttl time.Duration) *Tokens {
+	return &Tokens{method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public(), ttl: ttl}
+}
+
+// ユーザーのアクセストークンを発行する
+// トークンと有効期限を返す
+func (t *Tokens) Issue(user *models.User, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(t.ttl)
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.Itoa(user.ID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(t.method, claims).SignedString(t.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// アクセストークンを検証し、ユーザーIDを返す
+// 検証に失敗した場合は理由ごとに異なる UNAUTHENTICATED エラーを返す
+func (t *Tokens) Verify(token string, now time.Time) (int, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims,
+		func(*jwt.Token) (interface{}, error) { return t.verifyKey, nil },
+		jwt.WithValidMethods([]string{t.method.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	switch {
+	case err == nil:
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return 0, TokenExpiredError()
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return 0, TokenMalformedError(err.Error())
+	default:
+		return 0, TokenInvalidError(err.Error())
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, TokenInvalidError("invalid subject")
+	}
+	return userID, nil
+}
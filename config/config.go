@@ -0,0 +1,228 @@
+/*
+* アプリケーションの設定
+* 環境変数から読み込む
+ */
+
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// アプリケーションの設定値
+type Config struct {
+	DBPath           string // SQLiteデータベースファイルのパス
+	Production       bool   // 本番環境かどうか(エラー詳細の出力を抑制する)
+	MaxTitleLength   int    // 投稿タイトルの最大文字数
+	MaxContentLength int    // 投稿・コメント本文の最大文字数
+	BumpLimit        int    // スレッドを上げられる返信数の上限(これを超えた返信ではスレッドが上がらない)
+
+	UploadDir         string // 添付ファイルの保存先ディレクトリ
+	MaxAttachments    int    // 1つの投稿に添付できるファイルの数
+	MaxAttachmentSize int    // 添付ファイル1つの最大バイト数
+
+	AllowAnonymous bool               // ログインしていないユーザーの投稿を許可するか
+	JWTAlgorithm   string             // アクセストークンの署名アルゴリズム(HS256 または EdDSA)
+	JWTSecret      string             // HS256の署名鍵(空の場合は起動ごとに生成する)
+	JWTEd25519Key  ed25519.PrivateKey // EdDSAの署名鍵
+	TokenTTL       time.Duration      // アクセストークンの有効期間
+	AdminUsername  string             // 起動時に作成する管理者のユーザー名(空の場合は作成しない)
+	AdminPassword  string             // 起動時に作成する管理者のパスワード
+	PosterSalt     string             // トリップ・日替わりIDのハッシュに使うソルト(空の場合は起動ごとに生成する)
+	TrustedProxies []string           // X-Forwarded-For を信頼するプロキシのIPアドレスまたはCIDR(空の場合は接続元のアドレスを使う)
+}
+
+// デフォルトの設定値
+const (
+	DefaultDBPath            = "bbs.db"
+	DefaultMaxTitleLength    = 100
+	DefaultMaxContentLength  = 10000
+	DefaultTokenTTL          = 24 * time.Hour
+	DefaultBumpLimit         = 1000
+	DefaultUploadDir         = "uploads"
+	DefaultMaxAttachments    = 4
+	DefaultMaxAttachmentSize = 5 << 20 // 5MiB
+)
+
+// アクセストークンの署名アルゴリズム
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// デフォルトの設定を返す
+func Default() *Config {
+	return &Config{
+		DBPath:            DefaultDBPath,
+		MaxTitleLength:    DefaultMaxTitleLength,
+		MaxContentLength:  DefaultMaxContentLength,
+		BumpLimit:         DefaultBumpLimit,
+		UploadDir:         DefaultUploadDir,
+		MaxAttachments:    DefaultMaxAttachments,
+		MaxAttachmentSize: DefaultMaxAttachmentSize,
+		AllowAnonymous:    true,
+		JWTAlgorithm:      JWTAlgorithmHS256,
+		TokenTTL:          DefaultTokenTTL,
+	}
+}
+
+// 環境変数から設定を読み込む
+// 未設定の項目にはデフォルト値を使用する
+func Load() (*Config, error) {
+	cfg := Default()
+	cfg.DBPath = getEnv("BBS_DB_PATH", cfg.DBPath)
+	cfg.Production = getEnv("BBS_ENV", "development") == "production"
+
+	var err error
+	if cfg.MaxTitleLength, err = getEnvInt("BBS_MAX_TITLE_LENGTH", cfg.MaxTitleLength); err != nil {
+		return nil, err
+	}
+	if cfg.MaxContentLength, err = getEnvInt("BBS_MAX_CONTENT_LENGTH", cfg.MaxContentLength); err != nil {
+		return nil, err
+	}
+	if cfg.BumpLimit, err = getEnvInt("BBS_BUMP_LIMIT", cfg.BumpLimit); err != nil {
+		return nil, err
+	}
+	cfg.UploadDir = getEnv("BBS_UPLOAD_DIR", cfg.UploadDir)
+	if cfg.MaxAttachments, err = getEnvInt("BBS_MAX_ATTACHMENTS", cfg.MaxAttachments); err != nil {
+		return nil, err
+	}
+	if cfg.MaxAttachmentSize, err = getEnvInt("BBS_MAX_ATTACHMENT_SIZE", cfg.MaxAttachmentSize); err != nil {
+		return nil, err
+	}
+	if cfg.AllowAnonymous, err = getEnvBool("BBS_ALLOW_ANONYMOUS", cfg.AllowAnonymous); err != nil {
+		return nil, err
+	}
+	if cfg.TokenTTL, err = getEnvDuration("BBS_TOKEN_TTL", cfg.TokenTTL); err != nil {
+		return nil, err
+	}
+	cfg.AdminUsername = getEnv("BBS_ADMIN_USERNAME", "")
+	cfg.AdminPassword = getEnv("BBS_ADMIN_PASSWORD", "")
+	if cfg.AdminUsername != "" && cfg.AdminPassword == "" {
+		return nil, errors.New("BBS_ADMIN_PASSWORD is required when BBS_ADMIN_USERNAME is set")
+	}
+	cfg.PosterSalt = getEnv("BBS_POSTER_SALT", "")
+	cfg.TrustedProxies = getEnvList("BBS_TRUSTED_PROXIES")
+	for _, proxy := range cfg.TrustedProxies {
+		if !validProxy(proxy) {
+			return nil, fmt.Errorf("BBS_TRUSTED_PROXIES must be a list of IP addresses or CIDRs: %q", proxy)
+		}
+	}
+
+	cfg.JWTAlgorithm = getEnv("BBS_JWT_ALGORITHM", cfg.JWTAlgorithm)
+	switch cfg.JWTAlgorithm {
+	case JWTAlgorithmHS256:
+		cfg.JWTSecret = getEnv("BBS_JWT_SECRET", "")
+		// 本番環境では再起動でトークンが無効にならないよう、署名鍵の指定を必須とする
+		if cfg.Production && cfg.JWTSecret == "" {
+			return nil, errors.New("BBS_JWT_SECRET is required in production")
+		}
+	case JWTAlgorithmEdDSA:
+		path := getEnv("BBS_JWT_KEY_FILE", "")
+		if path == "" {
+			return nil, errors.New("BBS_JWT_KEY_FILE is required when BBS_JWT_ALGORITHM is EdDSA")
+		}
+		if cfg.JWTEd25519Key, err = loadEd25519Key(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("BBS_JWT_ALGORITHM must be %s or %s: %q", JWTAlgorithmHS256, JWTAlgorithmEdDSA, cfg.JWTAlgorithm)
+	}
+	return cfg, nil
+}
+
+// プロキシの指定がIPアドレスまたはCIDRかどうか
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// PEM形式(PKCS#8)のEd25519秘密鍵を読み込む
+func loadEd25519Key(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read BBS_JWT_KEY_FILE: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("BBS_JWT_KEY_FILE is not a PEM file: %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse BBS_JWT_KEY_FILE: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("BBS_JWT_KEY_FILE is not an Ed25519 private key: %s", path)
+	}
+	return edKey, nil
+}
+
+// 環境変数を取得し、未設定の場合はデフォルト値を返す
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// カンマ区切りの環境変数を取得する
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// 正の整数の環境変数を取得し、未設定の場合はデフォルト値を返す
+func getEnvInt(key string, fallback int) (int, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer: %q", key, value)
+	}
+	return n, nil
+}
+
+// 真偽値の環境変数を取得し、未設定の場合はデフォルト値を返す
+func getEnvBool(key string, fallback bool) (bool, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %q", key, value)
+	}
+	return b, nil
+}
+
+// 期間(例: 24h, 30m)の環境変数を取得し、未設定の場合はデフォルト値を返す
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration: %q", key, value)
+	}
+	return d, nil
+}
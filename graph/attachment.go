@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"bbs-gql-project/models"
+	"bbs-gql-project/validation"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// 添付ファイルを配信するパス(routers で同じパスに配信のハンドラを登録する)
+const AttachmentPath = "/v1/files/"
+
+// 検証済みのアップロードファイル
+type uploadedFile struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// アップロードされたファイルを読み込んで検証する
+// 入力値の誤りは errs に追加し、読み込みに失敗した場合だけエラーを返す
+func readUploads(errs *validation.Errors, limits validation.Limits, uploads []*graphql.Upload) ([]uploadedFile, error) {
+	// 数が上限を超える場合は内容を読み込まない(タイトルなど他の項目の誤りとは関係なく判定する)
+	if !limits.AttachmentCount(errs, len(uploads)) {
+		return nil, nil
+	}
+
+	files := make([]uploadedFile, 0, len(uploads))
+	for i, upload := range uploads {
+		field := fmt.Sprintf("attachments.%d", i)
+		if !limits.AttachmentSize(errs, field, upload.Size) {
+			continue
+		}
+		// 申告された大きさが正しいとは限らないため、上限を1バイト超えるまで読み込んで確認する
+		data, err := io.ReadAll(io.LimitReader(upload.File, int64(limits.MaxAttachmentSize)+1))
+		if err != nil {
+			return nil, models.BadRequestError("failed to read upload", err.Error())
+		}
+		filename, contentType := limits.Attachment(errs, field, upload.Filename, data)
+		files = append(files, uploadedFile{filename: filename, contentType: contentType, data: data})
+	}
+	return files, nil
+}
+
+// 添付ファイルの内容を保存し、添付ファイルとともに投稿を作成する
+// 内容の保存から投稿の作成までは blobMu の読み取りロックを保持し、未参照の内容の削除と並行しないようにする
+// 作成に失敗した場合は、保存した内容のうち他の添付ファイルが参照していないものを削除する
+func (r *Resolver) createPostWithAttachments(ctx context.Context, post *models.Post, files []uploadedFile, now time.Time) error {
+	keys := make([]string, 0, len(files))
+	err := func() error {
+		r.blobMu.RLock()
+		defer r.blobMu.RUnlock()
+
+		attachments := make([]models.Attachment, 0, len(files))
+		for _, file := range files {
+			key, err := r.BlobStore.Put(ctx, file.data)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			attachments = append(attachments, models.Attachment{
+				BlobKey:     key,
+				Filename:    file.filename,
+				ContentType: file.contentType,
+				Size:        len(file.data),
+				CreatedAt:   now,
+			})
+		}
+		return r.PostStore.Create(ctx, post, attachments)
+	}()
+	if err != nil {
+		r.deleteUnreferencedBlobs(ctx, keys)
+	}
+	return err
+}
+
+// どの添付ファイルからも参照されていない内容を削除する
+// 同じ内容は投稿の間で共有するため、blobMu の書き込みロックを保持して参照がないことを確認してから削除する
+// 削除に失敗しても呼び出し元の処理の結果を優先するため、ログに記録するだけにする
+func (r *Resolver) deleteUnreferencedBlobs(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	r.blobMu.Lock()
+	defer r.blobMu.Unlock()
+
+	for _, key := range keys {
+		referenced, err := r.AttachmentStore.Referenced(ctx, key)
+		if err != nil {
+			log.Printf("failed to check references to file %s: %v", key, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+		if err := r.BlobStore.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete unused file %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+)
+
+// ユーザー名またはパスワードが誤っている場合のエラー
+// ユーザーの存在を推測されないよう、どちらが誤っているかは区別しない
+func invalidCredentials() *models.AppError {
+	return models.UnauthenticatedError("invalid username or password", "invalid username or password")
+}
+
+// アクセストークンを発行し、登録・ログインの結果を作成する
+func (r *Resolver) authPayload(user *models.User) (*model.AuthPayload, error) {
+	token, expiresAt, err := r.Tokens.Issue(user, r.now())
+	if err != nil {
+		return nil, models.InternalServerError("failed to issue token", err.Error())
+	}
+	return &model.AuthPayload{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      toGraphUser(user),
+	}, nil
+}
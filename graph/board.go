@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+	"bbs-gql-project/validation"
+	"context"
+)
+
+// 掲示板内のスレッドの絞り込み条件を作成する
+func boardScope(board *model.Board, status models.ThreadStatus) (models.PostScope, error) {
+	boardID, err := parseID(board.ID)
+	if err != nil {
+		return models.PostScope{}, err
+	}
+	return models.PostScope{BoardID: boardID, Status: status}, nil
+}
+
+// 投稿先の掲示板を取得する
+// スラッグが省略された場合は既定の掲示板を返す
+func (r *Resolver) targetBoard(ctx context.Context, slug *string) (*models.Board, error) {
+	if slug == nil {
+		return r.BoardStore.Get(ctx, models.DefaultBoardID)
+	}
+	return r.BoardStore.GetBySlug(ctx, *slug)
+}
+
+// 投稿が属する掲示板の本文の上限を反映した入力値の上限設定を返す
+func (r *Resolver) postLimits(ctx context.Context, postID int) (validation.Limits, error) {
+	post, err := r.PostStore.Get(ctx, postID)
+	if err != nil {
+		return validation.Limits{}, err
+	}
+	board, err := r.BoardStore.Get(ctx, post.BoardID)
+	if err != nil {
+		return validation.Limits{}, err
+	}
+	return r.Limits.WithMaxContentLength(board.MaxPostLength), nil
+}
+
+// スラッグを指定して掲示板を変更する
+func (r *Resolver) updateBoard(ctx context.Context, slug string, change func(*models.Board)) (*model.Board, error) {
+	board, err := r.BoardStore.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	change(board)
+	if err := r.BoardStore.Update(ctx, board); err != nil {
+		return nil, err
+	}
+	return toGraphBoard(board), nil
+}
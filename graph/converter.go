@@ -0,0 +1,164 @@
+package graph
+
+import (
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+	"strconv"
+	"strings"
+)
+
+// モデル層の投稿データをGraphQLの投稿データに変換する
+func toGraphPost(post *models.Post) *model.Post {
+	p := &model.Post{
+		ID:            strconv.Itoa(post.ID),
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: toGraphContentFormat(post.Format),
+		CreatedAt:     post.CreatedAt,
+		UpdatedAt:     post.UpdatedAt,
+		LastBumpedAt:  post.LastBumpedAt,
+		Version:       post.Version,
+		AuthorID:      post.AuthorID,
+		BoardID:       post.BoardID,
+		Name:          post.Name,
+		Tripcode:      optionalString(post.Tripcode),
+		PosterID:      optionalString(post.PosterID),
+		Locked:        post.Locked,
+		Archived:      post.Archived(),
+	}
+	if post.Archived() {
+		archivedAt := post.ArchivedAt
+		p.ArchivedAt = &archivedAt
+	}
+	if post.Deleted() {
+		deletedAt := post.DeletedAt
+		p.DeletedAt = &deletedAt
+		p.DeletedByID = post.DeletedBy
+	}
+	return p
+}
+
+// モデル層の投稿の版をGraphQLの投稿の版に変換する
+func toGraphRevision(revision *models.Revision) *model.PostRevision {
+	return &model.PostRevision{
+		PostID:    revision.PostID,
+		Number:    revision.Number,
+		Title:     revision.Title,
+		Content:   revision.Content,
+		EditorID:  revision.EditorID,
+		CreatedAt: revision.CreatedAt,
+	}
+}
+
+// モデル層のアンカーの参照元をGraphQLの参照元に変換する
+func toGraphBacklink(backlink *models.Backlink) *model.Backlink {
+	b := &model.Backlink{Post: toGraphPost(&backlink.Post)}
+	if backlink.Comment != nil {
+		b.Comment = toGraphComment(backlink.Comment)
+	}
+	return b
+}
+
+// モデル層の添付ファイルをGraphQLの添付ファイルに変換する
+func toGraphAttachment(attachment *models.Attachment) *model.Attachment {
+	id := strconv.Itoa(attachment.ID)
+	return &model.Attachment{
+		ID:          id,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		URL:         AttachmentPath + id,
+		CreatedAt:   attachment.CreatedAt,
+	}
+}
+
+// 空文字列を null として扱う文字列を変換する
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// 本文の書式をGraphQLの列挙値に変換する
+func toGraphContentFormat(format models.ContentFormat) model.ContentFormat {
+	return model.ContentFormat(strings.ToUpper(string(format)))
+}
+
+// GraphQLの列挙値を本文の書式に変換する
+func toModelContentFormat(format model.ContentFormat) models.ContentFormat {
+	return models.ContentFormat(strings.ToLower(string(format)))
+}
+
+// GraphQLのIDをモデル層のIDに変換する
+func parseID(id string) (int, error) {
+	postID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, models.BadRequestError("invalid ID format", "invalid ID format")
+	}
+	return postID, nil
+}
+
+// モデル層のコメントデータをGraphQLのコメントデータに変換する
+func toGraphComment(comment *models.Comment) *model.Comment {
+	c := &model.Comment{
+		ID:        strconv.Itoa(comment.ID),
+		PostID:    strconv.Itoa(comment.PostID),
+		Content:   comment.Content,
+		Sage:      comment.Sage,
+		AuthorID:  comment.AuthorID,
+		CreatedAt: comment.CreatedAt,
+	}
+	if comment.ParentID != 0 {
+		parentID := strconv.Itoa(comment.ParentID)
+		c.ParentID = &parentID
+	}
+	return c
+}
+
+// expectedVersion引数を検証する
+// 省略された場合は0(バージョンを確認しない)を返す
+func parseExpectedVersion(expectedVersion *int) (int, error) {
+	if expectedVersion == nil {
+		return 0, nil
+	}
+	if *expectedVersion < 1 {
+		return 0, models.BadRequestError("invalid expectedVersion", "expectedVersion must be a positive integer")
+	}
+	return *expectedVersion, nil
+}
+
+// モデル層のユーザーデータをGraphQLのユーザーデータに変換する
+func toGraphUser(user *models.User) *model.User {
+	return &model.User{
+		ID:        strconv.Itoa(user.ID),
+		Username:  user.Username,
+		Role:      toGraphRole(user.Role),
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// 権限をGraphQLの列挙値に変換する
+func toGraphRole(role models.Role) model.Role {
+	return model.Role(strings.ToUpper(string(role)))
+}
+
+// GraphQLの列挙値を権限に変換する
+func toModelRole(role model.Role) models.Role {
+	return models.Role(strings.ToLower(string(role)))
+}
+
+// モデル層の掲示板データをGraphQLの掲示板データに変換する
+func toGraphBoard(board *models.Board) *model.Board {
+	return &model.Board{
+		ID:            strconv.Itoa(board.ID),
+		Slug:          board.Slug,
+		Name:          board.Name,
+		Description:   board.Description,
+		MaxThreads:    board.MaxThreads,
+		MaxReplies:    board.MaxReplies,
+		MaxPostLength: board.MaxPostLength,
+		Archived:      board.Archived,
+		CreatedAt:     board.CreatedAt,
+	}
+}
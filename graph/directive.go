@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"bbs-gql-project/auth"
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// スキーマで宣言したディレクティブの実装を作成する
+func NewDirectives(r *Resolver) DirectiveRoot {
+	return DirectiveRoot{
+		HasRole: r.hasRole,
+		IsOwner: r.isOwner,
+	}
+}
+
+// @hasRole: 指定した権限以上のユーザーのみ実行を許可する
+func (r *Resolver) hasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	user, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Role.AtLeast(toModelRole(role)) {
+		return nil, models.ForbiddenError("forbidden", fmt.Sprintf("%s role is required", role))
+	}
+	return next(ctx)
+}
+
+// @isOwner: 引数 id の投稿(またはコメント)の投稿者、または orRole 以上の権限のユーザーのみ実行を許可する
+// 匿名の投稿・コメントは orRole 以上の権限のユーザーのみ変更できる
+func (r *Resolver) isOwner(ctx context.Context, obj interface{}, next graphql.Resolver, orRole model.Role, object model.OwnedObject) (interface{}, error) {
+	user, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role.AtLeast(toModelRole(orRole)) {
+		return next(ctx)
+	}
+
+	fc := graphql.GetFieldContext(ctx)
+	id, ok := fc.Args["id"].(string)
+	if !ok {
+		return nil, models.InternalServerError("internal server error", fmt.Sprintf("@isOwner requires an id argument on %s", fc.Field.Name))
+	}
+	objectID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	authorID, err := r.authorOf(ctx, object, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if authorID != user.ID {
+		return nil, models.ForbiddenError("forbidden", fmt.Sprintf("only the author or a %s can modify this %s", orRole, strings.ToLower(object.String())))
+	}
+	return next(ctx)
+}
+
+// 投稿またはコメントの投稿者のユーザーIDを取得する(匿名の場合は0)
+func (r *Resolver) authorOf(ctx context.Context, object model.OwnedObject, id int) (int, error) {
+	if object == model.OwnedObjectComment {
+		comment, err := r.CommentStore.Get(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return comment.AuthorID, nil
+	}
+	post, err := r.PostStore.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return post.AuthorID, nil
+}
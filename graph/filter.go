@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+	"bbs-gql-project/validation"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// GraphQLの並び順の項目とモデル層の並び順の項目の対応
+var postOrderFields = map[model.PostOrderField]models.PostOrderField{
+	model.PostOrderFieldID:           models.OrderByID,
+	model.PostOrderFieldCreatedAt:    models.OrderByCreatedAt,
+	model.PostOrderFieldUpdatedAt:    models.OrderByUpdatedAt,
+	model.PostOrderFieldLastBumpedAt: models.OrderByLastBumpedAt,
+	model.PostOrderFieldTitle:        models.OrderByTitle,
+}
+
+// 投稿一覧の絞り込み条件をモデル層の条件に変換する
+// 入力値の誤りは入力項目ごとのエラーとしてまとめて返す
+func (r *Resolver) postScope(ctx context.Context, filter *model.PostFilter) (models.PostScope, error) {
+	var scope models.PostScope
+	if filter == nil {
+		return scope, nil
+	}
+
+	var errs validation.Errors
+	titleContains := filter.TitleContains.Value()
+	contentContains := filter.ContentContains.Value()
+	createdAfter := filter.CreatedAfter.Value()
+	createdBefore := filter.CreatedBefore.Value()
+	validation.PostFilter(&errs, titleContains, contentContains, createdAfter, createdBefore)
+	scope.TitleContains = derefOr(titleContains, "")
+	scope.ContentContains = derefOr(contentContains, "")
+	if createdAfter != nil {
+		scope.CreatedAfter = *createdAfter
+	}
+	if createdBefore != nil {
+		scope.CreatedBefore = *createdBefore
+	}
+	scope.HasReplies = filter.HasReplies.Value()
+
+	if authorID := filter.AuthorID.Value(); authorID != nil {
+		id, err := strconv.Atoi(*authorID)
+		if err != nil || id <= 0 {
+			errs.Add("filter.authorId", "must be a valid ID")
+		}
+		scope.AuthorID = id
+	}
+	if slug := filter.Board.Value(); slug != nil {
+		boardID, err := r.filterBoardID(ctx, &errs, *slug)
+		if err != nil {
+			return scope, err
+		}
+		scope.BoardID = boardID
+	}
+
+	return scope, errs.Err()
+}
+
+// 絞り込み条件の掲示板のスラッグからIDを求める
+// 存在しない掲示板の場合は、指定できるスラッグの一覧を入力エラーとして追加する
+func (r *Resolver) filterBoardID(ctx context.Context, errs *validation.Errors, slug string) (int, error) {
+	boards, err := r.BoardStore.List(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	slugs := make([]string, 0, len(boards))
+	for _, board := range boards {
+		if board.Slug == slug {
+			return board.ID, nil
+		}
+		slugs = append(slugs, board.Slug)
+	}
+	errs.Add("filter.board", "must be one of: "+strings.Join(slugs, ", "))
+	return 0, nil
+}
+
+// 投稿一覧の並び順をモデル層の並び順に変換する(省略した場合はIDの昇順)
+func toModelPostOrder(order *model.PostOrder) models.PostOrder {
+	if order == nil {
+		return models.PostOrder{}
+	}
+	direction := derefOr(order.Direction.Value(), model.OrderDirectionAsc)
+	return models.PostOrder{
+		Field: postOrderFields[order.Field],
+		Desc:  direction == model.OrderDirectionDesc,
+	}
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// GraphQLのコメントデータ
+// コメントしたユーザーと返信はフィールドリゾルバで取得するため、IDのみ保持する
+type Comment struct {
+	ID        string    `json:"id"`
+	PostID    string    `json:"postId"`
+	ParentID  *string   `json:"parentId,omitempty"`
+	Content   string    `json:"content"`
+	Sage      bool      `json:"sage"`
+	AuthorID  int       `json:"-"` // コメントしたユーザーのID(匿名の場合は0)
+	CreatedAt time.Time `json:"createdAt"`
+}
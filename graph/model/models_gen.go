@@ -2,24 +2,428 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int       `json:"size"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type AuthPayload struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user"`
+}
+
+type Backlink struct {
+	Post    *Post    `json:"post"`
+	Comment *Comment `json:"comment,omitempty"`
+}
+
+type Board struct {
+	ID              string          `json:"id"`
+	Slug            string          `json:"slug"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	MaxThreads      int             `json:"maxThreads"`
+	MaxReplies      int             `json:"maxReplies"`
+	MaxPostLength   int             `json:"maxPostLength"`
+	Archived        bool            `json:"archived"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	Threads         *PostConnection `json:"threads"`
+	ArchivedThreads *PostConnection `json:"archivedThreads"`
+}
+
+type BoardLimits struct {
+	MaxThreads    graphql.Omittable[*int] `json:"maxThreads,omitempty"`
+	MaxReplies    graphql.Omittable[*int] `json:"maxReplies,omitempty"`
+	MaxPostLength graphql.Omittable[*int] `json:"maxPostLength,omitempty"`
+}
+
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
 type Mutation struct {
 }
 
+type NewBoard struct {
+	Slug          string                     `json:"slug"`
+	Name          string                     `json:"name"`
+	Description   graphql.Omittable[*string] `json:"description,omitempty"`
+	MaxThreads    graphql.Omittable[*int]    `json:"maxThreads,omitempty"`
+	MaxReplies    graphql.Omittable[*int]    `json:"maxReplies,omitempty"`
+	MaxPostLength graphql.Omittable[*int]    `json:"maxPostLength,omitempty"`
+}
+
+type NewComment struct {
+	PostID   string                     `json:"postId"`
+	ParentID graphql.Omittable[*string] `json:"parentId,omitempty"`
+	Content  string                     `json:"content"`
+	Sage     graphql.Omittable[*bool]   `json:"sage,omitempty"`
+}
+
 type NewPost struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title         string                            `json:"title"`
+	Content       string                            `json:"content"`
+	Name          graphql.Omittable[*string]        `json:"name,omitempty"`
+	BoardSlug     graphql.Omittable[*string]        `json:"boardSlug,omitempty"`
+	ContentFormat graphql.Omittable[*ContentFormat] `json:"contentFormat,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+type PostConnection struct {
+	Edges      []*PostEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
 }
 
-type Post struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
+}
+
+type PostFilter struct {
+	TitleContains   graphql.Omittable[*string]    `json:"titleContains,omitempty"`
+	ContentContains graphql.Omittable[*string]    `json:"contentContains,omitempty"`
+	AuthorID        graphql.Omittable[*string]    `json:"authorId,omitempty"`
+	Board           graphql.Omittable[*string]    `json:"board,omitempty"`
+	CreatedAfter    graphql.Omittable[*time.Time] `json:"createdAfter,omitempty"`
+	CreatedBefore   graphql.Omittable[*time.Time] `json:"createdBefore,omitempty"`
+	HasReplies      graphql.Omittable[*bool]      `json:"hasReplies,omitempty"`
+}
+
+type PostOrder struct {
+	Field     PostOrderField                     `json:"field"`
+	Direction graphql.Omittable[*OrderDirection] `json:"direction,omitempty"`
+}
+
+type PostRevisionConnection struct {
+	Edges      []*PostRevisionEdge `json:"edges"`
+	PageInfo   *PageInfo           `json:"pageInfo"`
+	TotalCount int                 `json:"totalCount"`
+}
+
+type PostRevisionEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *PostRevision `json:"node"`
+}
+
+type PostSearchConnection struct {
+	Edges      []*PostSearchEdge `json:"edges"`
+	PageInfo   *PageInfo         `json:"pageInfo"`
+	TotalCount int               `json:"totalCount"`
+}
+
+type PostSearchEdge struct {
+	Cursor string  `json:"cursor"`
+	Node   *Post   `json:"node"`
+	Score  float64 `json:"score"`
 }
 
 type Query struct {
 }
 
+type RevisionDiff struct {
+	From    *PostRevision `json:"from"`
+	To      *PostRevision `json:"to"`
+	Title   string        `json:"title"`
+	Content string        `json:"content"`
+}
+
+type Subscription struct {
+}
+
+type UpdateComment struct {
+	Content graphql.Omittable[*string] `json:"content,omitempty"`
+}
+
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 type UpdatePost struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title   graphql.Omittable[*string] `json:"title,omitempty"`
+	Content graphql.Omittable[*string] `json:"content,omitempty"`
+}
+
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "PLAIN"
+	ContentFormatMarkdown ContentFormat = "MARKDOWN"
+)
+
+var AllContentFormat = []ContentFormat{
+	ContentFormatPlain,
+	ContentFormatMarkdown,
+}
+
+func (e ContentFormat) IsValid() bool {
+	switch e {
+	case ContentFormatPlain, ContentFormatMarkdown:
+		return true
+	}
+	return false
+}
+
+func (e ContentFormat) String() string {
+	return string(e)
+}
+
+func (e *ContentFormat) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ContentFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ContentFormat", str)
+	}
+	return nil
+}
+
+func (e ContentFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderDirection string
+
+const (
+	OrderDirectionAsc  OrderDirection = "ASC"
+	OrderDirectionDesc OrderDirection = "DESC"
+)
+
+var AllOrderDirection = []OrderDirection{
+	OrderDirectionAsc,
+	OrderDirectionDesc,
+}
+
+func (e OrderDirection) IsValid() bool {
+	switch e {
+	case OrderDirectionAsc, OrderDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e OrderDirection) String() string {
+	return string(e)
+}
+
+func (e *OrderDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderDirection", str)
+	}
+	return nil
+}
+
+func (e OrderDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OwnedObject string
+
+const (
+	OwnedObjectPost    OwnedObject = "POST"
+	OwnedObjectComment OwnedObject = "COMMENT"
+)
+
+var AllOwnedObject = []OwnedObject{
+	OwnedObjectPost,
+	OwnedObjectComment,
+}
+
+func (e OwnedObject) IsValid() bool {
+	switch e {
+	case OwnedObjectPost, OwnedObjectComment:
+		return true
+	}
+	return false
+}
+
+func (e OwnedObject) String() string {
+	return string(e)
+}
+
+func (e *OwnedObject) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OwnedObject(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OwnedObject", str)
+	}
+	return nil
+}
+
+func (e OwnedObject) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PostOrderField string
+
+const (
+	PostOrderFieldID           PostOrderField = "ID"
+	PostOrderFieldCreatedAt    PostOrderField = "CREATED_AT"
+	PostOrderFieldUpdatedAt    PostOrderField = "UPDATED_AT"
+	PostOrderFieldLastBumpedAt PostOrderField = "LAST_BUMPED_AT"
+	PostOrderFieldTitle        PostOrderField = "TITLE"
+)
+
+var AllPostOrderField = []PostOrderField{
+	PostOrderFieldID,
+	PostOrderFieldCreatedAt,
+	PostOrderFieldUpdatedAt,
+	PostOrderFieldLastBumpedAt,
+	PostOrderFieldTitle,
+}
+
+func (e PostOrderField) IsValid() bool {
+	switch e {
+	case PostOrderFieldID, PostOrderFieldCreatedAt, PostOrderFieldUpdatedAt, PostOrderFieldLastBumpedAt, PostOrderFieldTitle:
+		return true
+	}
+	return false
+}
+
+func (e PostOrderField) String() string {
+	return string(e)
+}
+
+func (e *PostOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PostOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PostOrderField", str)
+	}
+	return nil
+}
+
+func (e PostOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleMember    Role = "MEMBER"
+	RoleModerator Role = "MODERATOR"
+	RoleAdmin     Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleMember,
+	RoleModerator,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleMember, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ThreadOrder string
+
+const (
+	ThreadOrderBump    ThreadOrder = "BUMP"
+	ThreadOrderCreated ThreadOrder = "CREATED"
+)
+
+var AllThreadOrder = []ThreadOrder{
+	ThreadOrderBump,
+	ThreadOrderCreated,
+}
+
+func (e ThreadOrder) IsValid() bool {
+	switch e {
+	case ThreadOrderBump, ThreadOrderCreated:
+		return true
+	}
+	return false
+}
+
+func (e ThreadOrder) String() string {
+	return string(e)
+}
+
+func (e *ThreadOrder) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ThreadOrder(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ThreadOrder", str)
+	}
+	return nil
+}
+
+func (e ThreadOrder) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
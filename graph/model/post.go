@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// GraphQLの投稿データ
+// 投稿者などの関連データはフィールドリゾルバで取得するため、IDのみ保持する
+type Post struct {
+	ID            string        `json:"id"`
+	Title         string        `json:"title"`
+	Content       string        `json:"content"`
+	ContentFormat ContentFormat `json:"contentFormat"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+	LastBumpedAt  time.Time     `json:"lastBumpedAt"`
+	Version       int           `json:"version"`
+	AuthorID      int           `json:"-"` // 投稿したユーザーのID(匿名投稿の場合は0)
+	BoardID       int           `json:"-"` // 投稿が属する掲示板のID
+	Name          string        `json:"name"`
+	Tripcode      *string       `json:"tripcode,omitempty"`
+	PosterID      *string       `json:"posterId,omitempty"`
+	Locked        bool          `json:"locked"`
+	Archived      bool          `json:"archived"`
+	ArchivedAt    *time.Time    `json:"archivedAt,omitempty"`
+	DeletedAt     *time.Time    `json:"deletedAt,omitempty"`
+	DeletedByID   int           `json:"-"` // 投稿を削除したユーザーのID(削除されていない場合は0)
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// GraphQLの投稿の版
+// 編集者はフィールドリゾルバで取得するため、IDのみ保持する
+type PostRevision struct {
+	PostID    int       `json:"-"` // 版が属する投稿のID
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	EditorID  int       `json:"-"` // 作成・編集したユーザーのID(匿名・不明の場合は0)
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,480 @@
+package graph
+
+import (
+	"bbs-gql-project/graph/model"
+	"bbs-gql-project/models"
+	"bbs-gql-project/search"
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ページネーションの件数設定
+const (
+	defaultPageSize = 20  // first/lastが省略された場合の件数
+	maxPageSize     = 100 // 1ページで取得できる最大件数
+)
+
+// カーソルのプレフィックス(種類の異なるカーソルの取り違えを防ぐ)
+const (
+	postCursorPrefix     = "post:"
+	orderCursorPrefix    = "order:" // 並び順の項目と向き、ID、項目の値
+	commentCursorPrefix  = "comment:"
+	bumpCursorPrefix     = "bump:"
+	searchCursorPrefix   = "search:"   // 検索結果の順位(1始まり)
+	revisionCursorPrefix = "revision:" // 版番号
+)
+
+// IDから不透明なカーソル文字列を作成する
+func encodeCursor(prefix string, id int) string {
+	return base64.StdEncoding.EncodeToString([]byte(prefix + strconv.Itoa(id)))
+}
+
+// カーソル文字列からIDを取り出す
+func decodeCursor(prefix string, cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), prefix) {
+		return 0, models.BadRequestError("invalid cursor", "invalid cursor: "+cursor)
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(string(raw), prefix))
+	if err != nil || id <= 0 {
+		return 0, models.BadRequestError("invalid cursor", "invalid cursor: "+cursor)
+	}
+	return id, nil
+}
+
+// スレッドを上げられた日時とIDから不透明なカーソル文字列を作成する
+func encodeBumpCursor(post *models.Post) string {
+	raw := bumpCursorPrefix + strconv.FormatInt(post.LastBumpedAt.UnixNano(), 10) + ":" + strconv.Itoa(post.ID)
+	return base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+// カーソル文字列からスレッドを上げられた日時とIDを取り出す
+func decodeBumpCursor(cursor string) (time.Time, int, error) {
+	invalid := models.BadRequestError("invalid cursor", "invalid cursor: "+cursor)
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), bumpCursorPrefix) {
+		return time.Time{}, 0, invalid
+	}
+	at, id, ok := strings.Cut(strings.TrimPrefix(string(raw), bumpCursorPrefix), ":")
+	if !ok {
+		return time.Time{}, 0, invalid
+	}
+	nanos, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, invalid
+	}
+	postID, err := strconv.Atoi(id)
+	if err != nil || postID <= 0 {
+		return time.Time{}, 0, invalid
+	}
+	return time.Unix(0, nanos).UTC(), postID, nil
+}
+
+// 並び順の項目の値とIDから不透明なカーソル文字列を作成する
+// IDの昇順の場合は並び順を指定しない場合と同じカーソルにする
+func encodePostCursor(order models.PostOrder, post *models.Post) string {
+	if order == (models.PostOrder{}) || order == (models.PostOrder{Field: models.OrderByID}) {
+		return encodeCursor(postCursorPrefix, post.ID)
+	}
+	var value string
+	switch order.Field {
+	case models.OrderByCreatedAt:
+		value = strconv.FormatInt(post.CreatedAt.UnixNano(), 10)
+	case models.OrderByUpdatedAt:
+		value = strconv.FormatInt(post.UpdatedAt.UnixNano(), 10)
+	case models.OrderByLastBumpedAt:
+		value = strconv.FormatInt(post.LastBumpedAt.UnixNano(), 10)
+	case models.OrderByTitle:
+		value = post.Title
+	}
+	raw := orderCursorPrefix + orderKey(order) + ":" + strconv.Itoa(post.ID) + ":" + value
+	return base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+// カーソル文字列から並び順の位置を表す投稿(IDと並び順の項目の値だけを持つ)を取り出す
+// 別の並び順で作成されたカーソルは無効とする
+func decodePostCursor(order models.PostOrder, cursor string) (*models.Post, error) {
+	if order == (models.PostOrder{}) || order == (models.PostOrder{Field: models.OrderByID}) {
+		id, err := decodeCursor(postCursorPrefix, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &models.Post{ID: id}, nil
+	}
+
+	invalid := models.BadRequestError("invalid cursor", "invalid cursor: "+cursor)
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	rest, ok := strings.CutPrefix(string(raw), orderCursorPrefix+orderKey(order)+":")
+	if !ok {
+		return nil, invalid
+	}
+	id, value, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, invalid
+	}
+	post := &models.Post{}
+	if post.ID, err = strconv.Atoi(id); err != nil || post.ID <= 0 {
+		return nil, invalid
+	}
+	if order.Field == models.OrderByTitle {
+		post.Title = value
+		return post, nil
+	}
+	if order.Field == models.OrderByID {
+		return post, nil
+	}
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	at := time.Unix(0, nanos).UTC()
+	post.CreatedAt, post.UpdatedAt, post.LastBumpedAt = at, at, at
+	return post, nil
+}
+
+// カーソルに含める並び順の項目と向き
+func orderKey(order models.PostOrder) string {
+	if order.Desc {
+		return string(order.Field) + ":desc"
+	}
+	return string(order.Field) + ":asc"
+}
+
+// 並び順の位置を、その位置の投稿自身も含む範囲の境界に変換する
+// 項目の値が同じ投稿はIDの順に並ぶため、IDを1つずらす(After の境界は前に、Before の境界は後ろに)
+func inclusiveBound(order models.PostOrder, post *models.Post, after bool) *models.Post {
+	bound := *post
+	if order.Desc == after {
+		bound.ID++
+	} else {
+		bound.ID--
+	}
+	return &bound
+}
+
+// ページサイズの引数を検証する
+func pageSize(name string, value *int) (int, error) {
+	if value == nil {
+		return defaultPageSize, nil
+	}
+	if *value < 0 || *value > maxPageSize {
+		return 0, models.BadRequestError(
+			name+" is out of range",
+			name+" must be between 0 and "+strconv.Itoa(maxPageSize),
+		)
+	}
+	return *value, nil
+}
+
+// Relay仕様のカーソルページネーションで投稿一覧を取得する
+// scopeで掲示板やスレッドの状態を絞り込み、orderの順に並べる(カーソルには並び順の項目の値を含める)
+func postConnection(ctx context.Context, store models.PostStore, scope models.PostScope, order models.PostOrder, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	if first != nil && last != nil {
+		return nil, models.BadRequestError("first and last cannot be used together", "first and last cannot be used together")
+	}
+
+	var afterPost, beforePost *models.Post
+	var err error
+	if after != nil {
+		if afterPost, err = decodePostCursor(order, *after); err != nil {
+			return nil, err
+		}
+	}
+	if before != nil {
+		if beforePost, err = decodePostCursor(order, *before); err != nil {
+			return nil, err
+		}
+	}
+
+	backward := last != nil
+	var limit int
+	if backward {
+		limit, err = pageSize("last", last)
+	} else {
+		limit, err = pageSize("first", first)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 1件多く取得して次のページの有無を判定する
+	posts, err := store.Range(ctx, models.PostRange{
+		PostScope: scope,
+		Order:     order,
+		After:     afterPost,
+		Before:    beforePost,
+		Limit:     limit + 1,
+		Reverse:   backward,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+	if backward {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	pageInfo := &model.PageInfo{}
+	if backward {
+		pageInfo.HasPreviousPage = hasMore
+		if beforePost != nil {
+			pageInfo.HasNextPage, err = existsPost(ctx, store, models.PostRange{PostScope: scope, Order: order, After: inclusiveBound(order, beforePost, true)})
+		}
+	} else {
+		pageInfo.HasNextPage = hasMore
+		if afterPost != nil {
+			pageInfo.HasPreviousPage, err = existsPost(ctx, store, models.PostRange{PostScope: scope, Order: order, Before: inclusiveBound(order, afterPost, false)})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*model.PostEdge, 0, len(posts))
+	for i := range posts {
+		edges = append(edges, &model.PostEdge{
+			Cursor: encodePostCursor(order, &posts[i]),
+			Node:   toGraphPost(&posts[i]),
+		})
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	totalCount, err := store.Count(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PostConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// 範囲内に投稿が1件でも存在するかを判定する
+func existsPost(ctx context.Context, store models.PostStore, r models.PostRange) (bool, error) {
+	r.Limit = 1
+	posts, err := store.Range(ctx, r)
+	if err != nil {
+		return false, err
+	}
+	return len(posts) > 0, nil
+}
+
+// スレッドを上げられた順(新しい順)の投稿一覧を前方向のカーソルページネーションで取得する
+func bumpConnection(ctx context.Context, store models.PostStore, scope models.PostScope, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	if last != nil || before != nil {
+		return nil, models.BadRequestError("last and before cannot be used with orderBy: BUMP", "last and before cannot be used with orderBy: BUMP")
+	}
+	limit, err := pageSize("first", first)
+	if err != nil {
+		return nil, err
+	}
+	r := models.BumpRange{PostScope: scope, Limit: limit + 1}
+	if after != nil {
+		if r.AfterBumpedAt, r.AfterID, err = decodeBumpCursor(*after); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1件多く取得して次のページの有無を判定する
+	posts, err := store.RangeByBump(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	pageInfo := &model.PageInfo{
+		HasNextPage:     len(posts) > limit,
+		HasPreviousPage: r.AfterID > 0,
+	}
+	if pageInfo.HasNextPage {
+		posts = posts[:limit]
+	}
+
+	edges := make([]*model.PostEdge, 0, len(posts))
+	for i := range posts {
+		edges = append(edges, &model.PostEdge{
+			Cursor: encodeBumpCursor(&posts[i]),
+			Node:   toGraphPost(&posts[i]),
+		})
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	totalCount, err := store.Count(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PostConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// 全文検索の結果を前方向のカーソルページネーションで取得する
+func searchConnection(ctx context.Context, store models.PostStore, q search.Query, first *int, after *string) (*model.PostSearchConnection, error) {
+	limit, err := pageSize("first", first)
+	if err != nil {
+		return nil, err
+	}
+	var offset int
+	if after != nil {
+		if offset, err = decodeCursor(searchCursorPrefix, *after); err != nil {
+			return nil, err
+		}
+	}
+
+	hits, totalCount, err := store.Search(ctx, models.SearchRange{Query: q, Offset: offset, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	pageInfo := &model.PageInfo{
+		HasNextPage:     offset+len(hits) < totalCount,
+		HasPreviousPage: offset > 0,
+	}
+
+	edges := make([]*model.PostSearchEdge, 0, len(hits))
+	for i := range hits {
+		edges = append(edges, &model.PostSearchEdge{
+			Cursor: encodeCursor(searchCursorPrefix, offset+i+1),
+			Node:   toGraphPost(&hits[i].Post),
+			Score:  hits[i].Score,
+		})
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.PostSearchConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// 投稿の版の一覧を前方向のカーソルページネーションで取得する
+func revisionConnection(ctx context.Context, store models.RevisionStore, postID int, first *int, after *string) (*model.PostRevisionConnection, error) {
+	limit, err := pageSize("first", first)
+	if err != nil {
+		return nil, err
+	}
+	var afterNumber int
+	if after != nil {
+		if afterNumber, err = decodeCursor(revisionCursorPrefix, *after); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1件多く取得して次のページの有無を判定する
+	revisions, err := store.Range(ctx, models.RevisionRange{
+		PostID:      postID,
+		AfterNumber: afterNumber,
+		Limit:       limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pageInfo := &model.PageInfo{
+		HasNextPage:     len(revisions) > limit,
+		HasPreviousPage: afterNumber > 0,
+	}
+	if pageInfo.HasNextPage {
+		revisions = revisions[:limit]
+	}
+
+	edges := make([]*model.PostRevisionEdge, 0, len(revisions))
+	for i := range revisions {
+		edges = append(edges, &model.PostRevisionEdge{
+			Cursor: encodeCursor(revisionCursorPrefix, revisions[i].Number),
+			Node:   toGraphRevision(&revisions[i]),
+		})
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	totalCount, err := store.Count(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PostRevisionConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// 投稿または親コメントへのコメント一覧を前方向のカーソルページネーションで取得する
+func commentConnection(ctx context.Context, store models.CommentStore, postID int, parentID int, first *int, after *string) (*model.CommentConnection, error) {
+	limit, err := pageSize("first", first)
+	if err != nil {
+		return nil, err
+	}
+	var afterID int
+	if after != nil {
+		if afterID, err = decodeCursor(commentCursorPrefix, *after); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1件多く取得して次のページの有無を判定する
+	comments, err := store.Range(ctx, models.CommentRange{
+		PostID:   postID,
+		ParentID: parentID,
+		AfterID:  afterID,
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pageInfo := &model.PageInfo{
+		HasNextPage:     len(comments) > limit,
+		HasPreviousPage: afterID > 0,
+	}
+	if pageInfo.HasNextPage {
+		comments = comments[:limit]
+	}
+
+	edges := make([]*model.CommentEdge, 0, len(comments))
+	for i := range comments {
+		edges = append(edges, &model.CommentEdge{
+			Cursor: encodeCursor(commentCursorPrefix, comments[i].ID),
+			Node:   toGraphComment(&comments[i]),
+		})
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	totalCount, err := store.Count(ctx, postID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CommentConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"bbs-gql-project/validation"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// 部分更新の入力項目を取り出す
+// 省略された場合は nil を返し、null が明示された場合はエラーを追加して nil を返す
+func patchValue[T any](errs *validation.Errors, field string, v graphql.Omittable[*T]) *T {
+	value, ok := v.ValueOK()
+	if !ok {
+		return nil
+	}
+	if value == nil {
+		errs.Add(field, "must not be null")
+		return nil
+	}
+	return value
+}
+
+// 省略可能な入力項目の値を取り出す(省略された場合や null の場合は fallback を返す)
+func derefOr[T any](v *T, fallback T) T {
+	if v == nil {
+		return fallback
+	}
+	return *v
+}
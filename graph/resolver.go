@@ -1,7 +1,49 @@
 package graph
 
+import (
+	"bbs-gql-project/auth"
+	"bbs-gql-project/markup"
+	"bbs-gql-project/models"
+	"bbs-gql-project/poster"
+	"bbs-gql-project/pubsub"
+	"bbs-gql-project/validation"
+	"sync"
+	"time"
+)
+
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{}
+type Resolver struct {
+	PostStore       models.PostStore       // 投稿データの保存先
+	CommentStore    models.CommentStore    // コメントデータの保存先
+	UserStore       models.UserStore       // ユーザーデータの保存先
+	BoardStore      models.BoardStore      // 掲示板データの保存先
+	RevisionStore   models.RevisionStore   // 投稿の版の保存先
+	QuoteStore      models.QuoteStore      // アンカーによる参照の取得先
+	AttachmentStore models.AttachmentStore // 添付ファイルの属性の保存先
+	BlobStore       models.BlobStore       // 添付ファイルの内容の保存先
+	blobMu          sync.RWMutex           // 内容の保存・投稿の作成と、未参照の内容の削除を排他する
+	Clock           func() time.Time       // 現在時刻の取得(テストで差し替える)
+	Limits          validation.Limits      // 入力値の上限設定
+	BumpLimit       int                    // スレッドを上げられる返信数の上限
+
+	Tokens         *auth.Tokens // アクセストークンの発行
+	AllowAnonymous bool         // ログインしていないユーザーの投稿を許可するか
+
+	Poster *poster.Hasher   // トリップ・日替わりIDの作成
+	Markup *markup.Renderer // 本文のHTML変換(変換結果をキャッシュする)
+
+	PostEvents    *pubsub.Broker[models.PostEvent] // 投稿の変更イベントの配信
+	CommentEvents *pubsub.Broker[models.Comment]   // コメント作成イベントの配信
+}
+
+// 現在時刻を取得する
+// Clockが設定されていない場合はシステムの時刻を使用する
+func (r *Resolver) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock()
+}
@@ -1,90 +1,559 @@
 package graph
 
 import (
+	"bbs-gql-project/auth"
 	"bbs-gql-project/graph/model"
+	"bbs-gql-project/markup"
 	"bbs-gql-project/models"
+	"bbs-gql-project/poster"
+	"bbs-gql-project/validation"
 	"context"
 	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 )
 
+// 掲示板のスレッド一覧取得のリゾルバ
+func (r *boardResolver) Threads(ctx context.Context, obj *model.Board, orderBy *model.ThreadOrder, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	scope, err := boardScope(obj, models.LiveThreads)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != nil && *orderBy == model.ThreadOrderBump {
+		return bumpConnection(ctx, r.PostStore, scope, first, after, last, before)
+	}
+	return postConnection(ctx, r.PostStore, scope, models.PostOrder{}, first, after, last, before)
+}
+
+// 掲示板のアーカイブ済みスレッド一覧取得のリゾルバ
+func (r *boardResolver) ArchivedThreads(ctx context.Context, obj *model.Board, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	scope, err := boardScope(obj, models.ArchivedThreads)
+	if err != nil {
+		return nil, err
+	}
+	return postConnection(ctx, r.PostStore, scope, models.PostOrder{}, first, after, last, before)
+}
+
+// コメントしたユーザー取得のリゾルバ
+func (r *commentResolver) Author(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	if obj.AuthorID == 0 {
+		return nil, nil
+	}
+	user, err := r.UserStore.Get(ctx, obj.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// コメントへの返信一覧取得のリゾルバ
+func (r *commentResolver) Replies(ctx context.Context, obj *model.Comment, first *int, after *string) (*model.CommentConnection, error) {
+	commentID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	postID, err := parseID(obj.PostID)
+	if err != nil {
+		return nil, err
+	}
+	return commentConnection(ctx, r.CommentStore, postID, commentID, first, after)
+}
+
 // 新規投稿作成のリゾルバ
-func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPost) (*model.Post, error) {
-	if input.Title == "" {
-		return nil, models.BadRequestError("title is required", "title is required")
+func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPost, attachments []*graphql.Upload) (*model.Post, error) {
+	board, err := r.targetBoard(ctx, input.BoardSlug.Value())
+	if err != nil {
+		return nil, err
 	}
-	if input.Content == "" {
-		return nil, models.BadRequestError("content is required", "content is required")
+
+	var errs validation.Errors
+	r.Limits.WithMaxContentLength(board.MaxPostLength).PostFields(&errs, &input.Title, &input.Content)
+	nameInput := ""
+	if name := input.Name.Value(); name != nil && *name != "" {
+		nameInput = *name
+		validation.PosterName(&errs, nameInput)
+	}
+	files, err := readUploads(&errs, r.Limits, attachments)
+	if err != nil {
+		return nil, err
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	author := auth.UserFromContext(ctx)
+	if author == nil && !r.AllowAnonymous {
+		return nil, auth.TokenMissingError()
 	}
 
-	newID := len(models.Posts) + 1
+	now := r.now()
+	name, tripcode := r.Poster.Name(nameInput)
 	newPost := models.Post{
-		ID:      newID,
-		Title:   input.Title,
-		Content: input.Content,
-	}
-	models.Posts = append(models.Posts, newPost)
-	return &model.Post{
-		ID:      strconv.Itoa(newPost.ID),
-		Title:   newPost.Title,
-		Content: newPost.Content,
-	}, nil
+		Title:     input.Title,
+		Content:   input.Content,
+		Format:    toModelContentFormat(derefOr(input.ContentFormat.Value(), model.ContentFormatPlain)),
+		CreatedAt: now,
+		UpdatedAt: now,
+		BoardID:   board.ID,
+		Name:      name,
+		Tripcode:  tripcode,
+	}
+	if ip := poster.ClientIPFromContext(ctx); ip != "" {
+		newPost.PosterID = r.Poster.PosterID(ip, now)
+	}
+	if author != nil {
+		newPost.AuthorID = author.ID
+	}
+	if err := r.createPostWithAttachments(ctx, &newPost, files, now); err != nil {
+		return nil, err
+	}
+	r.PostEvents.Publish(models.PostEvent{Kind: models.PostCreated, Post: newPost})
+	return toGraphPost(&newPost), nil
 }
 
 // 投稿の更新のリゾルバ
-func (r *mutationResolver) UpdatePost(ctx context.Context, id string, input model.UpdatePost) (*model.Post, error) {
-	postID, err := strconv.Atoi(id)
-	if err != nil {
-		return nil, models.BadRequestError("invalid ID format", "invalid ID format")
-	}
-	for i, post := range models.Posts {
-		if post.ID == postID {
-			models.Posts[i].Title = input.Title
-			models.Posts[i].Content = input.Content
-			return &model.Post{
-				ID:      strconv.Itoa(models.Posts[i].ID),
-				Title:   models.Posts[i].Title,
-				Content: models.Posts[i].Content,
-			}, nil
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, input model.UpdatePost, expectedVersion *int) (*model.Post, error) {
+	postID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	version, err := parseExpectedVersion(expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		post, err := r.PostStore.Get(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+		board, err := r.BoardStore.Get(ctx, post.BoardID)
+		if err != nil {
+			return nil, err
+		}
+
+		// 指定された項目だけを検証する
+		var errs validation.Errors
+		title := patchValue(&errs, "title", input.Title)
+		content := patchValue(&errs, "content", input.Content)
+		r.Limits.WithMaxContentLength(board.MaxPostLength).PostFields(&errs, title, content)
+		if err := errs.Err(); err != nil {
+			return nil, err
+		}
+		if version != 0 && version != post.Version {
+			return nil, models.VersionConflictError(post.Version)
+		}
+		if title == nil && content == nil {
+			return toGraphPost(post), nil
+		}
+
+		if title != nil {
+			post.Title = *title
+		}
+		if content != nil {
+			post.Content = *content
+		}
+		post.UpdatedAt = r.now()
+		editorID := 0
+		if user := auth.UserFromContext(ctx); user != nil {
+			editorID = user.ID
 		}
+		err = r.PostStore.Update(ctx, post, editorID)
+		// expectedVersion が省略された場合は、取得後に他の更新があっても最新の内容に対して更新し直す
+		if version == 0 && models.IsKind(err, models.ErrorKindConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.PostEvents.Publish(models.PostEvent{Kind: models.PostUpdated, Post: *post})
+		return toGraphPost(post), nil
 	}
-	return nil, models.NotFoundError("post not found", "post not found")
 }
 
 // 投稿の削除のリゾルバ
-func (r *mutationResolver) DeletePost(ctx context.Context, id string) (bool, error) {
-	postID, err := strconv.Atoi(id)
+func (r *mutationResolver) DeletePost(ctx context.Context, id string, expectedVersion *int) (bool, error) {
+	postID, err := parseID(id)
+	if err != nil {
+		return false, err
+	}
+	version, err := parseExpectedVersion(expectedVersion)
+	if err != nil {
+		return false, err
+	}
+	deletedBy := 0
+	if user := auth.UserFromContext(ctx); user != nil {
+		deletedBy = user.ID
+	}
+	if err := r.PostStore.Delete(ctx, postID, version, deletedBy, r.now()); err != nil {
+		return false, err
+	}
+	r.PostEvents.Publish(models.PostEvent{Kind: models.PostDeleted, Post: models.Post{ID: postID}})
+	return true, nil
+}
+
+// 削除済みの投稿の復元のリゾルバ
+func (r *mutationResolver) RestorePost(ctx context.Context, id string) (*model.Post, error) {
+	postID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := r.PostStore.Restore(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphPost(post), nil
+}
+
+// 削除済みの投稿の完全な削除のリゾルバ
+func (r *mutationResolver) PurgeDeletedPosts(ctx context.Context, olderThan time.Time) (int, error) {
+	purged, blobKeys, err := r.PostStore.Purge(ctx, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	r.deleteUnreferencedBlobs(ctx, blobKeys)
+	return purged, nil
+}
+
+// コメント作成のリゾルバ
+func (r *mutationResolver) CreateComment(ctx context.Context, input model.NewComment) (*model.Comment, error) {
+	postID, err := parseID(input.PostID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := r.postLimits(ctx, postID)
 	if err != nil {
-		return false, models.BadRequestError("invalid ID format", "invalid ID format")
+		return nil, err
+	}
+	if err := limits.Comment(input.Content); err != nil {
+		return nil, err
 	}
-	for i, post := range models.Posts {
-		if post.ID == postID {
-			models.Posts = append(models.Posts[:i], models.Posts[i+1:]...)
-			return true, nil
+
+	newComment := models.Comment{
+		PostID:    postID,
+		Content:   input.Content,
+		Sage:      derefOr(input.Sage.Value(), false),
+		CreatedAt: r.now(),
+	}
+	if author := auth.UserFromContext(ctx); author != nil {
+		newComment.AuthorID = author.ID
+	}
+	if parentID := input.ParentID.Value(); parentID != nil {
+		if newComment.ParentID, err = parseID(*parentID); err != nil {
+			return nil, err
 		}
 	}
-	return false, models.NotFoundError("post not found", "post not found")
+	if err := r.CommentStore.Create(ctx, &newComment, r.BumpLimit); err != nil {
+		return nil, err
+	}
+	r.CommentEvents.Publish(newComment)
+	return toGraphComment(&newComment), nil
 }
 
-// 投稿一覧取得のリゾルバ
-func (r *queryResolver) GetAllPosts(ctx context.Context, page int, perPage int) ([]*model.Post, error) {
-	start := (page - 1) * (perPage)
-	end := start + (perPage)
+// コメント更新のリゾルバ
+func (r *mutationResolver) UpdateComment(ctx context.Context, id string, input model.UpdateComment) (*model.Comment, error) {
+	commentID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.CommentStore.Get(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := r.postLimits(ctx, comment.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 指定された項目だけを検証する
+	var errs validation.Errors
+	content := patchValue(&errs, "content", input.Content)
+	limits.CommentFields(&errs, content)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return toGraphComment(comment), nil
+	}
+
+	comment.Content = *content
+	if err := r.CommentStore.Update(ctx, comment); err != nil {
+		return nil, err
+	}
+	return toGraphComment(comment), nil
+}
+
+// コメント削除のリゾルバ
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (bool, error) {
+	commentID, err := parseID(id)
+	if err != nil {
+		return false, err
+	}
+	if err := r.CommentStore.Delete(ctx, commentID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	if start >= len(models.Posts) {
-		return []*model.Post{}, nil
+// アカウント登録のリゾルバ
+func (r *mutationResolver) Register(ctx context.Context, username string, password string) (*model.AuthPayload, error) {
+	if err := validation.Credentials(username, password); err != nil {
+		return nil, err
 	}
-	if end > len(models.Posts) {
-		end = len(models.Posts)
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, models.InternalServerError("failed to hash password", err.Error())
+	}
+
+	user := models.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         models.RoleMember,
+		CreatedAt:    r.now(),
+	}
+	if err := r.UserStore.Create(ctx, &user); err != nil {
+		return nil, err
+	}
+	return r.authPayload(&user)
+}
+
+// ログインのリゾルバ
+func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*model.AuthPayload, error) {
+	user, err := r.UserStore.GetByUsername(ctx, username)
+	if models.IsKind(err, models.ErrorKindNotFound) {
+		auth.CheckDummyPassword(password)
+		return nil, invalidCredentials()
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	result := models.Posts[start:end]
-	var postPointers []*model.Post
-	for _, post := range result {
-		postPointers = append(postPointers, &model.Post{
-			ID:      strconv.Itoa(post.ID),
-			Title:   post.Title,
-			Content: post.Content,
-		})
+	ok, err := auth.CheckPassword(user.PasswordHash, password)
+	if err != nil {
+		return nil, models.InternalServerError("failed to check password", err.Error())
+	}
+	if !ok {
+		return nil, invalidCredentials()
+	}
+	return r.authPayload(user)
+}
+
+// ユーザー権限変更のリゾルバ
+func (r *mutationResolver) UpdateUserRole(ctx context.Context, id string, role model.Role) (*model.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.UserStore.UpdateRole(ctx, userID, toModelRole(role))
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// 掲示板作成のリゾルバ
+func (r *mutationResolver) CreateBoard(ctx context.Context, input model.NewBoard) (*model.Board, error) {
+	description := derefOr(input.Description.Value(), "")
+	maxThreads := derefOr(input.MaxThreads.Value(), 0)
+	maxReplies := derefOr(input.MaxReplies.Value(), 0)
+	maxPostLength := derefOr(input.MaxPostLength.Value(), 0)
+	if err := validation.Board(input.Slug, input.Name, description, maxThreads, maxReplies, maxPostLength); err != nil {
+		return nil, err
+	}
+
+	board := models.Board{
+		Slug:          input.Slug,
+		Name:          input.Name,
+		Description:   description,
+		MaxThreads:    maxThreads,
+		MaxReplies:    maxReplies,
+		MaxPostLength: maxPostLength,
+		CreatedAt:     r.now(),
+	}
+	if err := r.BoardStore.Create(ctx, &board); err != nil {
+		return nil, err
+	}
+	return toGraphBoard(&board), nil
+}
+
+// 掲示板名変更のリゾルバ
+func (r *mutationResolver) RenameBoard(ctx context.Context, slug string, name string) (*model.Board, error) {
+	if err := validation.BoardName(name); err != nil {
+		return nil, err
+	}
+	return r.updateBoard(ctx, slug, func(board *models.Board) { board.Name = name })
+}
+
+// 掲示板アーカイブのリゾルバ
+func (r *mutationResolver) ArchiveBoard(ctx context.Context, slug string) (*model.Board, error) {
+	return r.updateBoard(ctx, slug, func(board *models.Board) { board.Archived = true })
+}
+
+// 掲示板の上限設定変更のリゾルバ
+func (r *mutationResolver) UpdateBoardLimits(ctx context.Context, slug string, input model.BoardLimits) (*model.Board, error) {
+	var errs validation.Errors
+	validation.BoardLimits(&errs, input.MaxThreads.Value(), input.MaxReplies.Value(), input.MaxPostLength.Value())
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return r.updateBoard(ctx, slug, func(board *models.Board) {
+		board.MaxThreads = derefOr(input.MaxThreads.Value(), board.MaxThreads)
+		board.MaxReplies = derefOr(input.MaxReplies.Value(), board.MaxReplies)
+		board.MaxPostLength = derefOr(input.MaxPostLength.Value(), board.MaxPostLength)
+	})
+}
+
+// 投稿本文のHTML取得のリゾルバ(Markdownの変換結果は投稿の版ごとにキャッシュする)
+func (r *postResolver) ContentHTML(ctx context.Context, obj *model.Post) (string, error) {
+	if obj.ContentFormat != model.ContentFormatMarkdown {
+		return markup.Plain(obj.Content), nil
+	}
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := r.Markup.Markdown(markup.Key{PostID: postID, Revision: obj.Version}, obj.Content)
+	if err != nil {
+		return "", models.InternalServerError("failed to render markdown", err.Error())
+	}
+	return rendered, nil
+}
+
+// 投稿者取得のリゾルバ
+func (r *postResolver) Author(ctx context.Context, obj *model.Post) (*model.User, error) {
+	if obj.AuthorID == 0 {
+		return nil, nil
+	}
+	user, err := r.UserStore.Get(ctx, obj.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// 投稿が属する掲示板取得のリゾルバ
+func (r *postResolver) Board(ctx context.Context, obj *model.Post) (*model.Board, error) {
+	board, err := r.BoardStore.Get(ctx, obj.BoardID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphBoard(board), nil
+}
+
+// 投稿を削除したユーザー取得のリゾルバ
+func (r *postResolver) DeletedBy(ctx context.Context, obj *model.Post) (*model.User, error) {
+	if obj.DeletedByID == 0 {
+		return nil, nil
+	}
+	user, err := r.UserStore.Get(ctx, obj.DeletedByID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// 投稿へのコメント一覧取得のリゾルバ
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, first *int, after *string) (*model.CommentConnection, error) {
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return commentConnection(ctx, r.CommentStore, postID, 0, first, after)
+}
+
+// 投稿の編集履歴取得のリゾルバ
+func (r *postResolver) Revisions(ctx context.Context, obj *model.Post, first *int, after *string) (*model.PostRevisionConnection, error) {
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return revisionConnection(ctx, r.RevisionStore, postID, first, after)
+}
+
+// 投稿の添付ファイル一覧取得のリゾルバ
+func (r *postResolver) Attachments(ctx context.Context, obj *model.Post) ([]*model.Attachment, error) {
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := r.AttachmentStore.List(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Attachment, 0, len(attachments))
+	for i := range attachments {
+		result = append(result, toGraphAttachment(&attachments[i]))
+	}
+	return result, nil
+}
+
+// 投稿がアンカーで参照している投稿一覧取得のリゾルバ
+func (r *postResolver) Quotes(ctx context.Context, obj *model.Post) ([]*model.Post, error) {
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := r.QuoteStore.Quoted(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Post, 0, len(posts))
+	for i := range posts {
+		result = append(result, toGraphPost(&posts[i]))
+	}
+	return result, nil
+}
+
+// 投稿をアンカーで参照している投稿・コメント一覧取得のリゾルバ
+func (r *postResolver) QuotedBy(ctx context.Context, obj *model.Post) ([]*model.Backlink, error) {
+	postID, err := parseID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	backlinks, err := r.QuoteStore.QuotedBy(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Backlink, 0, len(backlinks))
+	for i := range backlinks {
+		result = append(result, toGraphBacklink(&backlinks[i]))
+	}
+	return result, nil
+}
+
+// 版の編集者取得のリゾルバ
+func (r *postRevisionResolver) Editor(ctx context.Context, obj *model.PostRevision) (*model.User, error) {
+	if obj.EditorID == 0 {
+		return nil, nil
+	}
+	user, err := r.UserStore.Get(ctx, obj.EditorID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// 投稿一覧取得のリゾルバ
+func (r *queryResolver) GetAllPosts(ctx context.Context, page int, perPage int, filter *model.PostFilter, orderBy *model.PostOrder) ([]*model.Post, error) {
+	scope, err := r.postScope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := r.PostStore.List(ctx, models.PostPage{
+		PostScope: scope,
+		Order:     toModelPostOrder(orderBy),
+		Offset:    (page - 1) * perPage,
+		Limit:     perPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	postPointers := make([]*model.Post, 0, len(posts))
+	for i := range posts {
+		postPointers = append(postPointers, toGraphPost(&posts[i]))
 	}
 
 	return postPointers, nil
@@ -92,28 +561,176 @@ func (r *queryResolver) GetAllPosts(ctx context.Context, page int, perPage int)
 
 // 投稿の詳細取得のリゾルバ
 func (r *queryResolver) GetPost(ctx context.Context, id string) (*model.Post, error) {
-	postID, err := strconv.Atoi(id)
+	postID, err := parseID(id)
 	if err != nil {
-		return nil, models.BadRequestError("invalid ID format", "invalid ID format")
+		return nil, err
 	}
 
-	for _, post := range models.Posts {
-		if post.ID == postID {
-			return &model.Post{
-				ID:      strconv.Itoa(post.ID),
-				Title:   post.Title,
-				Content: post.Content,
-			}, nil
-		}
+	post, err := r.PostStore.Get(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphPost(post), nil
+}
+
+// 投稿一覧をカーソルページネーションで取得するリゾルバ
+func (r *queryResolver) Posts(ctx context.Context, first *int, after *string, last *int, before *string, filter *model.PostFilter, orderBy *model.PostOrder) (*model.PostConnection, error) {
+	scope, err := r.postScope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return postConnection(ctx, r.PostStore, scope, toModelPostOrder(orderBy), first, after, last, before)
+}
+
+// ログイン中のユーザー取得のリゾルバ
+func (r *queryResolver) Viewer(ctx context.Context) (*model.User, error) {
+	user, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphUser(user), nil
+}
+
+// 掲示板取得のリゾルバ
+func (r *queryResolver) Board(ctx context.Context, slug string) (*model.Board, error) {
+	board, err := r.BoardStore.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return toGraphBoard(board), nil
+}
+
+// 掲示板一覧取得のリゾルバ
+func (r *queryResolver) Boards(ctx context.Context, includeArchived *bool) ([]*model.Board, error) {
+	boards, err := r.BoardStore.List(ctx, derefOr(includeArchived, false))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Board, 0, len(boards))
+	for i := range boards {
+		result = append(result, toGraphBoard(&boards[i]))
+	}
+	return result, nil
+}
+
+// 投稿の全文検索のリゾルバ
+func (r *queryResolver) SearchPosts(ctx context.Context, query string, first *int, after *string) (*model.PostSearchConnection, error) {
+	q, err := validation.SearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return searchConnection(ctx, r.PostStore, q, first, after)
+}
+
+// 投稿の版取得のリゾルバ
+func (r *queryResolver) PostRevision(ctx context.Context, postID string, number int) (*model.PostRevision, error) {
+	id, err := parseID(postID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := r.RevisionStore.Get(ctx, id, number)
+	if err != nil {
+		return nil, err
 	}
-	return nil, models.NotFoundError("post not found", "post not found")
+	return toGraphRevision(revision), nil
 }
 
+// 投稿の版の差分取得のリゾルバ
+func (r *queryResolver) RevisionDiff(ctx context.Context, postID string, from int, to int) (*model.RevisionDiff, error) {
+	id, err := parseID(postID)
+	if err != nil {
+		return nil, err
+	}
+	fromRevision, err := r.RevisionStore.Get(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := r.RevisionStore.Get(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := models.DiffRevisions(fromRevision, toRevision)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevisionDiff{
+		From:    toGraphRevision(fromRevision),
+		To:      toGraphRevision(toRevision),
+		Title:   diff.Title,
+		Content: diff.Content,
+	}, nil
+}
+
+// 削除済みの投稿一覧取得のリゾルバ
+func (r *queryResolver) TrashedPosts(ctx context.Context, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	return postConnection(ctx, r.PostStore, models.PostScope{Deleted: true}, models.PostOrder{}, first, after, last, before)
+}
+
+// 投稿作成の通知のリゾルバ
+func (r *subscriptionResolver) PostCreated(ctx context.Context) (<-chan *model.Post, error) {
+	events := r.PostEvents.Subscribe(ctx)
+	return relay(ctx, events, func(e models.PostEvent) (*model.Post, bool) {
+		return toGraphPost(&e.Post), e.Kind == models.PostCreated
+	}), nil
+}
+
+// 投稿更新の通知のリゾルバ
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, id string) (<-chan *model.Post, error) {
+	postID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	events := r.PostEvents.Subscribe(ctx)
+	return relay(ctx, events, func(e models.PostEvent) (*model.Post, bool) {
+		return toGraphPost(&e.Post), e.Kind == models.PostUpdated && e.Post.ID == postID
+	}), nil
+}
+
+// 投稿削除の通知のリゾルバ
+func (r *subscriptionResolver) PostDeleted(ctx context.Context) (<-chan string, error) {
+	events := r.PostEvents.Subscribe(ctx)
+	return relay(ctx, events, func(e models.PostEvent) (string, bool) {
+		return strconv.Itoa(e.Post.ID), e.Kind == models.PostDeleted
+	}), nil
+}
+
+// コメント作成の通知のリゾルバ
+func (r *subscriptionResolver) CommentCreated(ctx context.Context, postID string) (<-chan *model.Comment, error) {
+	id, err := parseID(postID)
+	if err != nil {
+		return nil, err
+	}
+	events := r.CommentEvents.Subscribe(ctx)
+	return relay(ctx, events, func(c models.Comment) (*model.Comment, bool) {
+		return toGraphComment(&c), c.PostID == id
+	}), nil
+}
+
+// Board returns BoardResolver implementation.
+func (r *Resolver) Board() BoardResolver { return &boardResolver{r} }
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
+// PostRevision returns PostRevisionResolver implementation.
+func (r *Resolver) PostRevision() PostRevisionResolver { return &postRevisionResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+type boardResolver struct{ *Resolver }
+type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
+type postRevisionResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
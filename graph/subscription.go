@@ -0,0 +1,24 @@
+package graph
+
+import "context"
+
+// 購読したイベントを変換してサブスクリプションに渡す
+// convert が false を返したイベントは通知しない
+func relay[T any, U any](ctx context.Context, events <-chan T, convert func(T) (U, bool)) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for event := range events {
+			value, ok := convert(event)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -5,10 +5,31 @@
 package main
 
 import (
+	"context"
+	"log"
+
+	"bbs-gql-project/config"
+	"bbs-gql-project/models"
 	"bbs-gql-project/routers"
 )
 
 func main() {
-	r := routers.SetupRouter()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	store, err := models.OpenSQLiteStore(context.Background(), cfg.DBPath)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer store.Close()
+
+	blobs, err := models.NewDiskBlobStore(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("failed to open upload directory: %v", err)
+	}
+
+	r := routers.SetupRouter(routers.WithConfig(cfg), routers.WithStore(store), routers.WithBlobStore(blobs))
 	r.Run(":8080")
 }
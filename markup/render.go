@@ -0,0 +1,262 @@
+/*
+* 投稿本文のHTML変換
+* Markdown(CommonMark)をHTMLに変換し、許可した要素・属性以外を取り除く
+* 本文中のアンカー(>>N)は、書式によらず参照先の投稿へのリンクにする
+ */
+
+package markup
+
+import (
+	"bbs-gql-project/anchor"
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// 変換結果をキャッシュする件数(古いものから破棄する)
+const cacheSize = 1024
+
+// リンクに付けるrel属性(検索エンジンに評価させない、利用者の投稿であることを示す)
+const linkRel = "nofollow ugc"
+
+// アンカーのリンクに付けるclass属性
+const anchorClass = "anchor"
+
+// 画像として表示できるURL(添付ファイルの配信パス)
+var imageSrcPattern = regexp.MustCompile(`^/v1/files/[0-9]+$`)
+
+// 変換結果のキャッシュのキー
+// 投稿の内容は版ごとに変わらないため、投稿IDと版番号で変換結果を特定できる
+type Key struct {
+	PostID   int
+	Revision int
+}
+
+// 投稿本文をHTMLに変換する
+// Markdownの変換結果は投稿の版ごとにキャッシュし、一覧の取得のたびに変換し直さない
+// 複数のリクエストから並行して呼び出せる
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+	cache    *lru.Cache[Key, string]
+}
+
+// HTML変換を作成する
+func NewRenderer() *Renderer {
+	// cacheSizeは正の定数のため、エラーにならない
+	cache, _ := lru.New[Key, string](cacheSize)
+	return &Renderer{
+		markdown: goldmark.New(
+			goldmark.WithParser(newParser()),
+		),
+		policy: newPolicy(),
+		cache:  cache,
+	}
+}
+
+// Markdownの本文をHTMLに変換する
+// 生のHTMLは出力せず、変換結果も許可リストで無害化する
+func (r *Renderer) Markdown(key Key, content string) (string, error) {
+	if rendered, ok := r.cache.Get(key); ok {
+		return rendered, nil
+	}
+
+	var buf bytes.Buffer
+	if err := r.markdown.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	rendered := r.policy.Sanitize(buf.String())
+	r.cache.Add(key, rendered)
+	return rendered, nil
+}
+
+// プレーンテキストの本文をHTMLに変換する(特殊文字をエスケープし、改行を <br> にする)
+func Plain(content string) string {
+	var b strings.Builder
+	pos := 0
+	for _, m := range anchor.Find(content) {
+		b.WriteString(html.EscapeString(content[pos:m.Start]))
+		b.WriteString(`<a href="` + AnchorHref(m.From) + `" class="` + anchorClass + `">`)
+		b.WriteString(html.EscapeString(content[m.Start:m.End]))
+		b.WriteString(`</a>`)
+		pos = m.End
+	}
+	b.WriteString(html.EscapeString(content[pos:]))
+	return "<p>" + strings.ReplaceAll(b.String(), "\n", "<br>\n") + "</p>\n"
+}
+
+// アンカーのリンク先(参照先の投稿を表すページ内の位置)
+func AnchorHref(postID int) string {
+	return "#post-" + strconv.Itoa(postID)
+}
+
+// 変換後のHTMLで許可する要素と属性
+// CommonMarkが出力する要素のうち、スクリプトやイベントハンドラを含まないものだけを許可する
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "blockquote", "pre", "code", "em", "strong", "ul", "ol", "li",
+		"h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	// URLは http・https・mailto と相対URLのみ
+	p.AllowStandardURLs()
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("rel").Matching(regexp.MustCompile(`^` + linkRel + `$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^` + anchorClass + `$`)).OnElements("a")
+	// 画像は添付ファイルのみ(外部の画像による閲覧者の追跡を防ぐ)
+	p.AllowAttrs("src").Matching(imageSrcPattern).OnElements("img")
+	p.AllowAttrs("alt", "title").OnElements("img")
+	return p
+}
+
+// Markdownのパーサーを作成する
+// 行頭の >>N を引用として扱わないよう、引用のパーサーを置き換える
+func newParser() parser.Parser {
+	blockParsers := parser.DefaultBlockParsers()
+	for i := range blockParsers {
+		if blockParsers[i].Value == parser.NewBlockquoteParser() {
+			blockParsers[i].Value = blockquoteParser{blockParsers[i].Value.(parser.BlockParser)}
+		}
+	}
+	return parser.NewParser(
+		parser.WithBlockParsers(blockParsers...),
+		parser.WithInlineParsers(append(parser.DefaultInlineParsers(), util.Prioritized(anchorParser{}, 1000))...),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+		parser.WithASTTransformers(util.Prioritized(anchorTransformer{}, 50), util.Prioritized(relTransformer{}, 100)),
+	)
+}
+
+// 行頭のアンカーを除いて引用を解析するパーサー
+type blockquoteParser struct {
+	parser.BlockParser
+}
+
+// 行頭(字下げを除く)がアンカーの場合は引用を開始しない
+func (b blockquoteParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	if startsWithAnchor(reader) {
+		return nil, parser.NoChildren
+	}
+	return b.BlockParser.Open(parent, reader, pc)
+}
+
+// 行頭がアンカーの場合は引用を終える
+func (b blockquoteParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if startsWithAnchor(reader) {
+		return parser.Close
+	}
+	return b.BlockParser.Continue(node, reader, pc)
+}
+
+// 読み取り位置の行が(字下げを除いて)アンカーで始まるかを判定する
+func startsWithAnchor(reader text.Reader) bool {
+	line, _ := reader.PeekLine()
+	_, ok := anchor.Prefix(strings.TrimLeft(string(line), " \t"))
+	return ok
+}
+
+// アンカーを解析するインラインのパーサー(リンクへの置き換えは anchorTransformer で行う)
+type anchorParser struct{}
+
+// > で始まる位置で呼び出す
+func (anchorParser) Trigger() []byte {
+	return []byte{'>'}
+}
+
+// 読み取り位置のアンカーを解析中のアンカーのノードにする
+func (anchorParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	m, ok := anchor.Prefix(string(line))
+	if !ok {
+		return nil
+	}
+	block.Advance(m.End)
+	node := &anchorNode{postID: m.From}
+	node.AppendChild(node, ast.NewTextSegment(text.NewSegment(segment.Start, segment.Start+m.End)))
+	return node
+}
+
+// 解析中のアンカーのノードの種類
+var kindAnchor = ast.NewNodeKind("Anchor")
+
+// 解析中のアンカー
+// リンクのテキストの中にリンクがあるとリンクとして解析されないため、解析を終えてからリンクに置き換える
+type anchorNode struct {
+	ast.BaseInline
+	postID int // 参照先の投稿ID(範囲の場合は始まり)
+}
+
+// ノードの種類を返す
+func (n *anchorNode) Kind() ast.NodeKind {
+	return kindAnchor
+}
+
+// デバッグ用にノードの内容を出力する
+func (n *anchorNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"PostID": strconv.Itoa(n.postID)}, nil)
+}
+
+// 解析中のアンカーをリンクに置き換えるASTの変換
+// [>>4](https://example.com) のようなリンクや画像の中のアンカーはテキストのままにする
+type anchorTransformer struct{}
+
+// 文書内の解析中のアンカーを、参照先へのリンクまたはテキストに置き換える
+func (anchorTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	anchors := []*anchorNode{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if a, ok := n.(*anchorNode); ok && entering {
+			anchors = append(anchors, a)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, a := range anchors {
+		parent := a.Parent()
+		if hasLinkAncestor(a) {
+			for child := a.FirstChild(); child != nil; child = a.FirstChild() {
+				parent.InsertBefore(parent, a, child)
+			}
+			parent.RemoveChild(parent, a)
+			continue
+		}
+		link := ast.NewLink()
+		link.Destination = []byte(AnchorHref(a.postID))
+		link.SetAttributeString("class", []byte(anchorClass))
+		for child := a.FirstChild(); child != nil; child = a.FirstChild() {
+			link.AppendChild(link, child)
+		}
+		parent.ReplaceChild(parent, a, link)
+	}
+}
+
+// 祖先にリンクまたは画像があるかを判定する
+func hasLinkAncestor(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindLink || p.Kind() == ast.KindImage {
+			return true
+		}
+	}
+	return false
+}
+
+// リンクにrel属性を付けるASTの変換
+type relTransformer struct{}
+
+// 文書内のすべてのリンク(自動リンクを含む)にrel属性を設定する
+func (relTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && (n.Kind() == ast.KindLink || n.Kind() == ast.KindAutoLink) {
+			n.SetAttributeString("rel", []byte(linkRel))
+		}
+		return ast.WalkContinue, nil
+	})
+}
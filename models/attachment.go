@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// 投稿の添付ファイル
+// ファイルの内容は BlobStore に保存し、ここには内容のキーと属性だけを持つ
+type Attachment struct {
+	ID          int       `json:"id"`
+	PostID      int       `json:"post_id"`
+	BlobKey     string    `json:"blob_key"` // BlobStore のキー(内容のSHA-256)
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"` // 内容から判定したMIMEタイプ
+	Size        int       `json:"size"`         // バイト数
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,168 @@
+/*
+* 添付ファイルの内容の保存先
+ */
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// ファイルの内容の保存先を表すインターフェース
+// 内容のSHA-256をキーとするため、同じ内容は1つだけ保存され、保存した内容は変更されない
+type BlobStore interface {
+	// 内容を保存してキーを返す(同じ内容が保存済みの場合は何もしない)
+	Put(ctx context.Context, data []byte) (string, error)
+	// キーを指定して内容を開く(存在しない場合は Not Found)
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// キーを指定して内容を削除する(存在しない場合は何もしない)
+	Delete(ctx context.Context, key string) error
+}
+
+// キーの形式(SHA-256の16進表記)
+var blobKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// 内容からキーを求める
+func blobKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// メモリ上に内容を保持する保存先(テスト・開発用)
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// メモリ上の保存先を作成する
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[string][]byte{}}
+}
+
+// 内容を保存してキーを返す
+func (s *MemoryBlobStore) Put(ctx context.Context, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := blobKey(data)
+	if _, ok := s.blobs[key]; !ok {
+		s.blobs[key] = bytes.Clone(data)
+	}
+	return key, nil
+}
+
+// キーを指定して内容を開く
+func (s *MemoryBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, blobNotFound()
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+// キーを指定して内容を削除する
+func (s *MemoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.blobs, key)
+	return nil
+}
+
+// Close が何もしない io.ReadSeekCloser
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+// 何もしない
+func (nopCloser) Close() error {
+	return nil
+}
+
+// ローカルディスクに内容を保存する保存先
+// キーの先頭2文字のディレクトリに分けて保存する(例: dir/ab/abcdef...)
+type DiskBlobStore struct {
+	dir string
+}
+
+// ディレクトリを指定してローカルディスクの保存先を作成する(ディレクトリがなければ作成する)
+func NewDiskBlobStore(dir string) (*DiskBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload directory: %w", err)
+	}
+	return &DiskBlobStore{dir: dir}, nil
+}
+
+// キーに対応するファイルのパス
+func (s *DiskBlobStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+// 内容を保存してキーを返す
+// 書き込み途中のファイルを読まれないよう、一時ファイルに書き込んでから名前を変更する
+func (s *DiskBlobStore) Put(ctx context.Context, data []byte) (string, error) {
+	key := blobKey(data)
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", InternalServerError("failed to store file", err.Error())
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp-*")
+	if err != nil {
+		return "", InternalServerError("failed to store file", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", InternalServerError("failed to store file", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return "", InternalServerError("failed to store file", err.Error())
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", InternalServerError("failed to store file", err.Error())
+	}
+	return key, nil
+}
+
+// キーを指定して内容を開く
+// キーの形式を確認し、保存先のディレクトリの外を開かないようにする
+func (s *DiskBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	if !blobKeyPattern.MatchString(key) {
+		return nil, blobNotFound()
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobNotFound()
+	}
+	if err != nil {
+		return nil, InternalServerError("failed to open file", err.Error())
+	}
+	return f, nil
+}
+
+// キーを指定して内容を削除する
+func (s *DiskBlobStore) Delete(ctx context.Context, key string) error {
+	if !blobKeyPattern.MatchString(key) {
+		return nil
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return InternalServerError("failed to delete file", err.Error())
+	}
+	return nil
+}
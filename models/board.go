@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 掲示板データ構造体を定義する
+// 掲示板はスレッド(投稿)をまとめるカテゴリで、スラッグで識別する
+type Board struct {
+	ID            int       `json:"id"`
+	Slug          string    `json:"slug"` // URLに使用する識別子(英小文字・数字・ハイフン)
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	MaxThreads    int       `json:"max_threads"`     // スレッド数の上限(超えると古いスレッドをアーカイブする。0の場合は無制限)
+	MaxReplies    int       `json:"max_replies"`     // スレッドごとの返信数の上限(達するとロックする。0の場合は無制限)
+	MaxPostLength int       `json:"max_post_length"` // 本文の最大文字数(0の場合は全体の設定に従う)
+	Archived      bool      `json:"archived"`        // アーカイブ済み(新しいスレッドを作成できない)
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// 既定の掲示板のID(掲示板を指定せずに作成した投稿が属する)
+const DefaultBoardID = 1
+
+// 既定の掲示板
+// ストアの初期データとして使用する
+var DefaultBoard = Board{
+	ID:        DefaultBoardID,
+	Slug:      "general",
+	Name:      "総合",
+	CreatedAt: seedTime(0),
+}
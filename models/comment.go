@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// コメントデータ構造体を定義する
+// ParentIDが0のコメントは投稿への直接の返信、それ以外はコメントへの返信を表す
+type Comment struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	ParentID  int       `json:"parent_id"`
+	Content   string    `json:"content"`
+	Sage      bool      `json:"sage"`      // trueの場合はスレッドを上げない
+	AuthorID  int       `json:"author_id"` // コメントしたユーザーのID(匿名の場合は0)
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,15 +1,49 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
+// エラー種別(クライアントが分岐に使う安定した文字列)
+const (
+	ErrorKindBadRequest      = "BAD_REQUEST"
+	ErrorKindUnauthenticated = "UNAUTHENTICATED"
+	ErrorKindForbidden       = "FORBIDDEN"
+	ErrorKindNotFound        = "NOT_FOUND"
+	ErrorKindConflict        = "CONFLICT"
+	ErrorKindThreadArchived  = "THREAD_ARCHIVED"
+	ErrorKindThreadLocked    = "THREAD_LOCKED"
+	ErrorKindInternalServer  = "INTERNAL_SERVER_ERROR"
+)
+
+// HTTPステータスコードごとのデフォルトのエラー種別
+var errorKinds = map[int]string{
+	http.StatusBadRequest:          ErrorKindBadRequest,
+	http.StatusUnauthorized:        ErrorKindUnauthenticated,
+	http.StatusForbidden:           ErrorKindForbidden,
+	http.StatusNotFound:            ErrorKindNotFound,
+	http.StatusConflict:            ErrorKindConflict,
+	http.StatusInternalServerError: ErrorKindInternalServer,
+}
+
 // カスタムエラー構造体
 type AppError struct {
-	Code    int    `json:"code"`    // HTTPステータスコード
+	Code    int          `json:"code"`             // HTTPステータスコード
+	Kind    string       `json:"kind"`             // エラー種別(NOT_FOUND など)
+	Message string       `json:"message"`          // エラーメッセージ
+	Detail  string       `json:"detail"`           // エラー詳細
+	Fields  []FieldError `json:"fields,omitempty"` // 入力項目ごとのエラー(入力検証エラーの場合)
+	// クライアントに返す追加情報(GraphQLの extensions に出力する)
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// 入力項目ごとのエラー
+type FieldError struct {
+	Field   string `json:"field"`   // 入力項目名
 	Message string `json:"message"` // エラーメッセージ
-	Detail  string `json:"detail"`  // エラー詳細
 }
 
 // Errorメソッドを実装して、エラーメッセージを返す
@@ -17,15 +51,37 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("code: %d, message: %s, detail: %s", e.Code, e.Message, e.Detail)
 }
 
+// エラーが指定した種別のAppErrorかを判定する
+func IsKind(err error, kind string) bool {
+	var appErr *AppError
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
+
 // 新しいエラーを作成
+// エラー種別はHTTPステータスコードから決定する
 func NewAppError(code int, message string, detail string) *AppError {
+	kind, ok := errorKinds[code]
+	if !ok {
+		kind = ErrorKindInternalServer
+	}
 	return &AppError{
 		Code:    code,
+		Kind:    kind,
 		Message: message,
 		Detail:  detail,
 	}
 }
 
+// 401 Unauthorized
+func UnauthenticatedError(message string, detail string) *AppError {
+	return NewAppError(http.StatusUnauthorized, message, detail)
+}
+
+// 403 Forbidden
+func ForbiddenError(message string, detail string) *AppError {
+	return NewAppError(http.StatusForbidden, message, detail)
+}
+
 // 404 Not Found
 func NotFoundError(message string, detail string) *AppError {
 	return NewAppError(http.StatusNotFound, message, detail)
@@ -36,6 +92,46 @@ func BadRequestError(message string, detail string) *AppError {
 	return NewAppError(http.StatusBadRequest, message, detail)
 }
 
+// 400 Bad Request (入力検証エラー)
+// すべての入力項目のエラーをまとめて返す
+func ValidationError(fields []FieldError) *AppError {
+	details := make([]string, 0, len(fields))
+	for _, f := range fields {
+		details = append(details, f.Field+": "+f.Message)
+	}
+	err := BadRequestError("validation failed", strings.Join(details, "; "))
+	err.Fields = fields
+	return err
+}
+
+// 409 Conflict
+func ConflictError(message string, detail string) *AppError {
+	return NewAppError(http.StatusConflict, message, detail)
+}
+
+// 409 Conflict (バージョンの不一致)
+// 現在のバージョンを extensions の currentVersion に含める
+func VersionConflictError(currentVersion int) *AppError {
+	err := ConflictError("version conflict", fmt.Sprintf("the post has been modified (current version: %d)", currentVersion))
+	err.Extensions = map[string]interface{}{"currentVersion": currentVersion}
+	return err
+}
+
+// 409 Conflict (アーカイブ済みのスレッドへの返信)
+func ThreadArchivedError() *AppError {
+	err := ConflictError("thread is archived", "the thread has been archived and does not accept new replies")
+	err.Kind = ErrorKindThreadArchived
+	return err
+}
+
+// 409 Conflict (ロックされたスレッドへの返信)
+// 掲示板の上限はロックした後に変更される場合があるため、スレッドの実際の返信数を示す
+func ThreadLockedError(replies int) *AppError {
+	err := ConflictError("thread is locked", fmt.Sprintf("the thread is locked with %d replies and is read-only", replies))
+	err.Kind = ErrorKindThreadLocked
+	return err
+}
+
 // 500 Internal Server Error
 func InternalServerError(message string, detail string) *AppError {
 	return NewAppError(http.StatusInternalServerError, message, detail)
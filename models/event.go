@@ -0,0 +1,16 @@
+package models
+
+// 投稿イベントの種類
+type PostEventKind int
+
+const (
+	PostCreated PostEventKind = iota + 1
+	PostUpdated
+	PostDeleted
+)
+
+// 投稿に対する変更イベント
+type PostEvent struct {
+	Kind PostEventKind
+	Post Post // 削除イベントの場合はIDのみ設定される
+}
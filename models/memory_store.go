@@ -0,0 +1,867 @@
+/*
+* インメモリのストア実装
+ */
+
+package models
+
+import (
+	"bbs-gql-project/anchor"
+	"bbs-gql-project/search"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// スライスに投稿・コメント・ユーザーデータを保持するストア
+// Ginは複数のリクエストを並行して処理するため、読み書きはロックで保護する
+type MemoryStore struct {
+	mu               sync.RWMutex
+	posts            []Post
+	nextPostID       int // 次に採番する投稿ID(削除されたIDは再利用しない)
+	comments         []Comment
+	nextCommentID    int // 次に採番するコメントID
+	users            []User
+	nextUserID       int // 次に採番するユーザーID
+	boards           []Board
+	nextBoardID      int           // 次に採番する掲示板ID
+	revisions        []Revision    // 投稿の版(作成順のため、同じ投稿の版は版番号の昇順に並ぶ)
+	attachments      []Attachment  // 添付ファイルの属性(内容は BlobStore に保存する)
+	nextAttachmentID int           // 次に採番する添付ファイルID
+	index            *search.Index // 投稿の全文検索用のインデックス
+	quotes           *anchor.Index // アンカーによる参照の索引
+}
+
+// 初期データを指定してインメモリのストアを作成する
+// 引数のスライスはコピーされるため、呼び出し元のデータは変更されない
+// 掲示板は既定の掲示板のみを作成し、掲示板が指定されていない投稿は既定の掲示板に属する
+func NewMemoryStore(seed []Post) *MemoryStore {
+	posts := make([]Post, len(seed))
+	copy(posts, seed)
+
+	nextID := 1
+	index := search.NewIndex()
+	quotes := anchor.NewIndex()
+	revisions := make([]Revision, 0, len(posts))
+	for i := range posts {
+		if posts[i].ID >= nextID {
+			nextID = posts[i].ID + 1
+		}
+		if posts[i].Version == 0 {
+			posts[i].Version = 1
+		}
+		if posts[i].BoardID == 0 {
+			posts[i].BoardID = DefaultBoardID
+		}
+		if posts[i].LastBumpedAt.IsZero() {
+			posts[i].LastBumpedAt = posts[i].CreatedAt
+		}
+		if posts[i].Format == "" {
+			posts[i].Format = FormatPlain
+		}
+		if !posts[i].Deleted() {
+			index.Add(posts[i].ID, posts[i].Title, posts[i].Content)
+		}
+		quotes.Set(anchor.Source{PostID: posts[i].ID}, posts[i].Content)
+		// 初期データは現在の内容だけを版として持つ
+		revisions = append(revisions, newRevision(&posts[i], posts[i].AuthorID, posts[i].UpdatedAt))
+	}
+	return &MemoryStore{
+		posts:            posts,
+		nextPostID:       nextID,
+		nextCommentID:    1,
+		nextUserID:       1,
+		boards:           []Board{DefaultBoard},
+		nextBoardID:      DefaultBoardID + 1,
+		revisions:        revisions,
+		nextAttachmentID: 1,
+		index:            index,
+		quotes:           quotes,
+	}
+}
+
+// 投稿データの保存先を返す
+func (s *MemoryStore) Posts() PostStore {
+	return (*memoryPostStore)(s)
+}
+
+// コメントデータの保存先を返す
+func (s *MemoryStore) Comments() CommentStore {
+	return (*memoryCommentStore)(s)
+}
+
+// ユーザーデータの保存先を返す
+func (s *MemoryStore) Users() UserStore {
+	return (*memoryUserStore)(s)
+}
+
+// 掲示板データの保存先を返す
+func (s *MemoryStore) Boards() BoardStore {
+	return (*memoryBoardStore)(s)
+}
+
+// 投稿の版の保存先を返す
+func (s *MemoryStore) Revisions() RevisionStore {
+	return (*memoryRevisionStore)(s)
+}
+
+// アンカーによる参照の取得先を返す
+func (s *MemoryStore) Quotes() QuoteStore {
+	return (*memoryQuoteStore)(s)
+}
+
+// 添付ファイルの属性の保存先を返す
+func (s *MemoryStore) Attachments() AttachmentStore {
+	return (*memoryAttachmentStore)(s)
+}
+
+// 投稿のインデックスを探す(ロックは呼び出し元で取得する)
+func (s *MemoryStore) postIndex(id int) int {
+	for i := range s.posts {
+		if s.posts[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// 掲示板のインデックスを探す(ロックは呼び出し元で取得する)
+func (s *MemoryStore) boardIndex(id int) int {
+	for i := range s.boards {
+		if s.boards[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// 削除されていない投稿のインデックスを探す(ロックは呼び出し元で取得する)
+func (s *MemoryStore) livePostIndex(id int) int {
+	i := s.postIndex(id)
+	if i < 0 || s.posts[i].Deleted() {
+		return -1
+	}
+	return i
+}
+
+// コメントのインデックスを探す(ロックは呼び出し元で取得する)
+// 削除済みの投稿へのコメントは見つからないものとして扱う
+func (s *MemoryStore) commentIndex(id int) int {
+	for i := range s.comments {
+		if s.comments[i].ID == id {
+			if s.livePostIndex(s.comments[i].PostID) < 0 {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// 条件に一致するコメントと、その返信をすべて削除する(ロックは呼び出し元で取得する)
+func (s *MemoryStore) deleteCommentsWhere(match func(*Comment) bool) {
+	deleted := map[int]bool{}
+	// IDは親より子が大きいため、昇順に走査すれば子孫まで辿れる
+	for i := range s.comments {
+		c := &s.comments[i]
+		if match(c) || deleted[c.ParentID] {
+			deleted[c.ID] = true
+		}
+	}
+
+	kept := s.comments[:0]
+	for _, c := range s.comments {
+		if deleted[c.ID] {
+			s.quotes.Remove(anchor.Source{PostID: c.PostID, CommentID: c.ID})
+			continue
+		}
+		kept = append(kept, c)
+	}
+	s.comments = kept
+}
+
+// インメモリの投稿ストア
+type memoryPostStore MemoryStore
+
+// IDを指定して投稿を取得する
+func (s *memoryPostStore) Get(ctx context.Context, id int) (*Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := (*MemoryStore)(s).livePostIndex(id)
+	if i < 0 {
+		return nil, postNotFound()
+	}
+	post := s.posts[i]
+	return &post, nil
+}
+
+// 絞り込み条件に一致する投稿を並び順に従ってoffset件目からlimit件取得する
+func (s *memoryPostStore) List(ctx context.Context, p PostPage) ([]Post, error) {
+	if err := p.Order.Field.check(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := (*MemoryStore)(s).matcher(p.PostScope)
+	result := []Post{}
+	for i := range s.posts {
+		if matches(&s.posts[i]) {
+			result = append(result, s.posts[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return p.Order.less(&result[i], &result[j]) })
+
+	if p.Offset < 0 || p.Offset >= len(result) || p.Limit <= 0 {
+		return []Post{}, nil
+	}
+	result = result[p.Offset:]
+	if len(result) > p.Limit {
+		result = result[:p.Limit]
+	}
+	return result, nil
+}
+
+// 絞り込み条件の判定関数を作成する(ロックは呼び出し元で取得する)
+// コメントの有無を条件にする場合は、コメントのある投稿を先に集計する
+func (s *MemoryStore) matcher(scope PostScope) func(*Post) bool {
+	replied := map[int]bool{}
+	if scope.HasReplies != nil {
+		for i := range s.comments {
+			replied[s.comments[i].PostID] = true
+		}
+	}
+	hasReplies := func(postID int) bool { return replied[postID] }
+	return func(post *Post) bool { return scope.contains(post, hasReplies) }
+}
+
+// 並び順の位置を指定して投稿を取得する
+func (s *memoryPostStore) Range(ctx context.Context, r PostRange) ([]Post, error) {
+	if err := r.Order.Field.check(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := (*MemoryStore)(s).matcher(r.PostScope)
+	result := []Post{}
+	for i := range s.posts {
+		if r.inRange(&s.posts[i]) && matches(&s.posts[i]) {
+			result = append(result, s.posts[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if r.Reverse {
+			return r.Order.less(&result[j], &result[i])
+		}
+		return r.Order.less(&result[i], &result[j])
+	})
+	if len(result) > r.Limit {
+		result = result[:r.Limit]
+	}
+	return result, nil
+}
+
+// スレッドを上げられた順に投稿を取得する
+func (s *memoryPostStore) RangeByBump(ctx context.Context, r BumpRange) ([]Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := (*MemoryStore)(s).matcher(r.PostScope)
+	result := []Post{}
+	for i := range s.posts {
+		if r.inRange(&s.posts[i]) && matches(&s.posts[i]) {
+			result = append(result, s.posts[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastBumpedAt.Equal(result[j].LastBumpedAt) {
+			return result[i].LastBumpedAt.After(result[j].LastBumpedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if len(result) > r.Limit {
+		result = result[:r.Limit]
+	}
+	return result, nil
+}
+
+// 絞り込み条件に一致する投稿の数を取得する
+func (s *memoryPostStore) Count(ctx context.Context, scope PostScope) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return (*MemoryStore)(s).countPosts(scope), nil
+}
+
+// 絞り込み条件に一致する投稿の数を数える(ロックは呼び出し元で取得する)
+func (s *MemoryStore) countPosts(scope PostScope) int {
+	matches := s.matcher(scope)
+	count := 0
+	for i := range s.posts {
+		if matches(&s.posts[i]) {
+			count++
+		}
+	}
+	return count
+}
+
+// タイトルと本文を全文検索する
+func (s *memoryPostStore) Search(ctx context.Context, r SearchRange) ([]SearchHit, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits := s.index.Search(r.Query)
+	result := []SearchHit{}
+	for _, hit := range r.page(hits) {
+		if i := (*MemoryStore)(s).postIndex(hit.ID); i >= 0 {
+			result = append(result, SearchHit{Post: s.posts[i], Score: hit.Score})
+		}
+	}
+	return result, len(hits), nil
+}
+
+// 最後に上げられたのが古い順にn件のスレッドをアーカイブする(ロックは呼び出し元で取得する)
+func (s *MemoryStore) archiveThreads(boardID int, n int, now time.Time) {
+	matches := s.matcher(PostScope{BoardID: boardID, Status: LiveThreads})
+	live := []*Post{}
+	for i := range s.posts {
+		if matches(&s.posts[i]) {
+			live = append(live, &s.posts[i])
+		}
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if !live[i].LastBumpedAt.Equal(live[j].LastBumpedAt) {
+			return live[i].LastBumpedAt.Before(live[j].LastBumpedAt)
+		}
+		return live[i].ID < live[j].ID
+	})
+	for i := 0; i < n && i < len(live); i++ {
+		live[i].ArchivedAt = now
+	}
+}
+
+// 投稿を新規作成する
+func (s *memoryPostStore) Create(ctx context.Context, post *Post, attachments []Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := (*MemoryStore)(s).boardIndex(post.BoardID)
+	if b < 0 {
+		return boardNotFound()
+	}
+	board := &s.boards[b]
+	if err := checkNewThread(board); err != nil {
+		return err
+	}
+	live := (*MemoryStore)(s).countPosts(PostScope{BoardID: post.BoardID, Status: LiveThreads})
+	if n := threadsToArchive(board, live); n > 0 {
+		(*MemoryStore)(s).archiveThreads(post.BoardID, n, post.CreatedAt)
+	}
+
+	post.ID = s.nextPostID
+	post.Version = 1
+	post.LastBumpedAt = post.CreatedAt
+	s.nextPostID++
+	s.posts = append(s.posts, *post)
+	s.revisions = append(s.revisions, newRevision(post, post.AuthorID, post.CreatedAt))
+	for i := range attachments {
+		attachments[i].ID = s.nextAttachmentID
+		attachments[i].PostID = post.ID
+		s.nextAttachmentID++
+		s.attachments = append(s.attachments, attachments[i])
+	}
+	s.index.Add(post.ID, post.Title, post.Content)
+	s.quotes.Set(anchor.Source{PostID: post.ID}, post.Content)
+	return nil
+}
+
+// 投稿を更新する
+func (s *memoryPostStore) Update(ctx context.Context, post *Post, editorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := (*MemoryStore)(s).livePostIndex(post.ID)
+	if i < 0 {
+		return postNotFound()
+	}
+	if s.posts[i].Version != post.Version {
+		return VersionConflictError(s.posts[i].Version)
+	}
+	// スレッドの状態(上げられた日時・ロック・アーカイブ)はストア側で管理するため変更しない
+	stored := &s.posts[i]
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.UpdatedAt = post.UpdatedAt
+	stored.Version++
+	*post = *stored
+	s.revisions = append(s.revisions, newRevision(post, editorID, post.UpdatedAt))
+	s.index.Add(post.ID, post.Title, post.Content)
+	s.quotes.Set(anchor.Source{PostID: post.ID}, post.Content)
+	return nil
+}
+
+// IDを指定して投稿を削除済みにする
+func (s *memoryPostStore) Delete(ctx context.Context, id int, expectedVersion int, deletedBy int, deletedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := (*MemoryStore)(s).livePostIndex(id)
+	if i < 0 {
+		return postNotFound()
+	}
+	if expectedVersion != 0 && s.posts[i].Version != expectedVersion {
+		return VersionConflictError(s.posts[i].Version)
+	}
+	s.posts[i].DeletedAt = deletedAt
+	s.posts[i].DeletedBy = deletedBy
+	s.index.Remove(id)
+	return nil
+}
+
+// 削除済みの投稿を元に戻す
+func (s *memoryPostStore) Restore(ctx context.Context, id int) (*Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := (*MemoryStore)(s).postIndex(id)
+	if i < 0 || !s.posts[i].Deleted() {
+		return nil, deletedPostNotFound()
+	}
+	s.posts[i].DeletedAt = time.Time{}
+	s.posts[i].DeletedBy = 0
+	post := s.posts[i]
+	s.index.Add(post.ID, post.Title, post.Content)
+	return &post, nil
+}
+
+// olderThanより前に削除された投稿を完全に削除する
+func (s *memoryPostStore) Purge(ctx context.Context, olderThan time.Time) (int, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := map[int]bool{}
+	kept := s.posts[:0]
+	for _, post := range s.posts {
+		if post.Deleted() && post.DeletedAt.Before(olderThan) {
+			purged[post.ID] = true
+			continue
+		}
+		kept = append(kept, post)
+	}
+	s.posts = kept
+	(*MemoryStore)(s).deleteCommentsWhere(func(c *Comment) bool { return purged[c.PostID] })
+	revisions := s.revisions[:0]
+	for _, revision := range s.revisions {
+		if !purged[revision.PostID] {
+			revisions = append(revisions, revision)
+		}
+	}
+	s.revisions = revisions
+	attachments := s.attachments[:0]
+	blobKeys := []string{}
+	for _, attachment := range s.attachments {
+		if purged[attachment.PostID] {
+			blobKeys = append(blobKeys, attachment.BlobKey)
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	s.attachments = attachments
+	for id := range purged {
+		s.quotes.RemovePost(id)
+	}
+	return len(purged), blobKeys, nil
+}
+
+// インメモリのコメントストア
+type memoryCommentStore MemoryStore
+
+// IDを指定してコメントを取得する
+func (s *memoryCommentStore) Get(ctx context.Context, id int) (*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := (*MemoryStore)(s).commentIndex(id)
+	if i < 0 {
+		return nil, commentNotFound()
+	}
+	comment := s.comments[i]
+	return &comment, nil
+}
+
+// 投稿または親コメントを指定してコメントを取得する
+func (s *memoryCommentStore) Range(ctx context.Context, r CommentRange) ([]Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Comment{}
+	for i := range s.comments {
+		if len(result) >= r.Limit {
+			break
+		}
+		if r.matches(&s.comments[i]) {
+			result = append(result, s.comments[i])
+		}
+	}
+	return result, nil
+}
+
+// 投稿または親コメントを指定してコメント数を取得する
+func (s *memoryCommentStore) Count(ctx context.Context, postID int, parentID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for i := range s.comments {
+		if s.comments[i].PostID == postID && s.comments[i].ParentID == parentID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// コメントを新規作成する
+func (s *memoryCommentStore) Create(ctx context.Context, comment *Comment, bumpLimit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := (*MemoryStore)(s).livePostIndex(comment.PostID)
+	if p < 0 {
+		return postNotFound()
+	}
+	if comment.ParentID != 0 {
+		i := (*MemoryStore)(s).commentIndex(comment.ParentID)
+		if i < 0 {
+			return commentNotFound()
+		}
+		if s.comments[i].PostID != comment.PostID {
+			return parentMismatch()
+		}
+	}
+
+	replies := 0
+	for i := range s.comments {
+		if s.comments[i].PostID == comment.PostID {
+			replies++
+		}
+	}
+	post := &s.posts[p]
+	board := &s.boards[(*MemoryStore)(s).boardIndex(post.BoardID)]
+	if err := checkReply(post, board, replies); err != nil {
+		return err
+	}
+	if bumps(comment, replies, bumpLimit) {
+		post.LastBumpedAt = comment.CreatedAt
+	}
+	if locks(board, replies+1) {
+		post.Locked = true
+	}
+
+	comment.ID = s.nextCommentID
+	s.nextCommentID++
+	s.comments = append(s.comments, *comment)
+	s.quotes.Set(anchor.Source{PostID: comment.PostID, CommentID: comment.ID}, comment.Content)
+	return nil
+}
+
+// コメントの本文を更新する
+func (s *memoryCommentStore) Update(ctx context.Context, comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := (*MemoryStore)(s).commentIndex(comment.ID)
+	if i < 0 {
+		return commentNotFound()
+	}
+	if s.posts[(*MemoryStore)(s).livePostIndex(s.comments[i].PostID)].Archived() {
+		return ThreadArchivedError()
+	}
+	s.comments[i].Content = comment.Content
+	s.quotes.Set(anchor.Source{PostID: comment.PostID, CommentID: comment.ID}, comment.Content)
+	return nil
+}
+
+// IDを指定してコメントを削除する
+func (s *memoryCommentStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if (*MemoryStore)(s).commentIndex(id) < 0 {
+		return commentNotFound()
+	}
+	(*MemoryStore)(s).deleteCommentsWhere(func(c *Comment) bool { return c.ID == id })
+	return nil
+}
+
+// インメモリの版ストア
+type memoryRevisionStore MemoryStore
+
+// 投稿IDと版番号を指定して版を取得する
+func (s *memoryRevisionStore) Get(ctx context.Context, postID int, number int) (*Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if (*MemoryStore)(s).livePostIndex(postID) < 0 {
+		return nil, postNotFound()
+	}
+	for _, revision := range s.revisions {
+		if revision.PostID == postID && revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, revisionNotFound()
+}
+
+// 投稿の版を版番号の昇順で取得する
+func (s *memoryRevisionStore) Range(ctx context.Context, r RevisionRange) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Revision{}
+	for _, revision := range s.revisions {
+		if len(result) >= r.Limit {
+			break
+		}
+		if revision.PostID == r.PostID && revision.Number > r.AfterNumber {
+			result = append(result, revision)
+		}
+	}
+	return result, nil
+}
+
+// 投稿の版の数を取得する
+func (s *memoryRevisionStore) Count(ctx context.Context, postID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, revision := range s.revisions {
+		if revision.PostID == postID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// インメモリのアンカーの参照ストア
+type memoryQuoteStore MemoryStore
+
+// 投稿の本文が参照している投稿をIDの昇順で取得する
+func (s *memoryQuoteStore) Quoted(ctx context.Context, postID int) ([]Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Post{}
+	for _, id := range s.quotes.Targets(postID) {
+		if i := (*MemoryStore)(s).livePostIndex(id); i >= 0 {
+			result = append(result, s.posts[i])
+		}
+	}
+	return result, nil
+}
+
+// 投稿を参照している投稿・コメントを取得する
+func (s *memoryQuoteStore) QuotedBy(ctx context.Context, postID int) ([]Backlink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Backlink{}
+	for _, source := range s.quotes.Sources(postID) {
+		p := (*MemoryStore)(s).livePostIndex(source.PostID)
+		if p < 0 {
+			continue
+		}
+		backlink := Backlink{Post: s.posts[p]}
+		if source.CommentID != 0 {
+			c := (*MemoryStore)(s).commentIndex(source.CommentID)
+			if c < 0 {
+				continue
+			}
+			comment := s.comments[c]
+			backlink.Comment = &comment
+		}
+		result = append(result, backlink)
+	}
+	return result, nil
+}
+
+// インメモリの添付ファイルストア
+type memoryAttachmentStore MemoryStore
+
+// IDを指定して添付ファイルを取得する
+func (s *memoryAttachmentStore) Get(ctx context.Context, id int) (*Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, attachment := range s.attachments {
+		if attachment.ID == id && (*MemoryStore)(s).livePostIndex(attachment.PostID) >= 0 {
+			return &attachment, nil
+		}
+	}
+	return nil, attachmentNotFound()
+}
+
+// 投稿の添付ファイルをIDの昇順で取得する
+func (s *memoryAttachmentStore) List(ctx context.Context, postID int) ([]Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Attachment{}
+	for _, attachment := range s.attachments {
+		if attachment.PostID == postID {
+			result = append(result, attachment)
+		}
+	}
+	return result, nil
+}
+
+// 内容のキーを参照している添付ファイルがあるか
+func (s *memoryAttachmentStore) Referenced(ctx context.Context, blobKey string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, attachment := range s.attachments {
+		if attachment.BlobKey == blobKey {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// インメモリのユーザーストア
+type memoryUserStore MemoryStore
+
+// IDを指定してユーザーを取得する
+func (s *memoryUserStore) Get(ctx context.Context, id int) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, userNotFound()
+}
+
+// ユーザー名を指定してユーザーを取得する
+func (s *memoryUserStore) GetByUsername(ctx context.Context, username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if strings.EqualFold(user.Username, username) {
+			return &user, nil
+		}
+	}
+	return nil, userNotFound()
+}
+
+// ユーザーを新規作成する
+func (s *memoryUserStore) Create(ctx context.Context, user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, user.Username) {
+			return usernameTaken()
+		}
+	}
+	user.ID = s.nextUserID
+	s.nextUserID++
+	s.users = append(s.users, *user)
+	return nil
+}
+
+// ユーザーの権限を変更する
+func (s *memoryUserStore) UpdateRole(ctx context.Context, id int, role Role) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID == id {
+			s.users[i].Role = role
+			user := s.users[i]
+			return &user, nil
+		}
+	}
+	return nil, userNotFound()
+}
+
+// インメモリの掲示板ストア
+type memoryBoardStore MemoryStore
+
+// IDを指定して掲示板を取得する
+func (s *memoryBoardStore) Get(ctx context.Context, id int) (*Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := (*MemoryStore)(s).boardIndex(id)
+	if i < 0 {
+		return nil, boardNotFound()
+	}
+	board := s.boards[i]
+	return &board, nil
+}
+
+// スラッグを指定して掲示板を取得する
+func (s *memoryBoardStore) GetBySlug(ctx context.Context, slug string) (*Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, board := range s.boards {
+		if board.Slug == slug {
+			return &board, nil
+		}
+	}
+	return nil, boardNotFound()
+}
+
+// 掲示板の一覧を取得する
+func (s *memoryBoardStore) List(ctx context.Context, includeArchived bool) ([]Board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Board{}
+	for _, board := range s.boards {
+		if includeArchived || !board.Archived {
+			result = append(result, board)
+		}
+	}
+	return result, nil
+}
+
+// 掲示板を新規作成する
+func (s *memoryBoardStore) Create(ctx context.Context, board *Board) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.boards {
+		if b.Slug == board.Slug {
+			return slugTaken()
+		}
+	}
+	board.ID = s.nextBoardID
+	s.nextBoardID++
+	s.boards = append(s.boards, *board)
+	return nil
+}
+
+// 掲示板を更新する
+func (s *memoryBoardStore) Update(ctx context.Context, board *Board) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := (*MemoryStore)(s).boardIndex(board.ID)
+	if i < 0 {
+		return boardNotFound()
+	}
+	board.Slug = s.boards[i].Slug
+	s.boards[i] = *board
+	return nil
+}
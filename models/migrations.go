@@ -0,0 +1,229 @@
+/*
+* データベースのマイグレーション
+* バージョン番号順に前方向にのみ適用する(ロールバックは行わない)
+ */
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// マイグレーション1件分の定義
+type migration struct {
+	Version int    // バージョン番号(1から連番)
+	Name    string // 内容の説明
+	SQL     string // 適用するSQL
+}
+
+// マイグレーション一覧
+// 適用済みのマイグレーションは変更せず、必ず末尾に追加すること
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "create posts",
+		SQL: `CREATE TABLE posts (
+			id      INTEGER PRIMARY KEY AUTOINCREMENT,
+			title   TEXT NOT NULL,
+			content TEXT NOT NULL
+		)`,
+	},
+	{
+		Version: 2,
+		Name:    "create comments",
+		SQL: `CREATE TABLE comments (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			parent_id  INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+			content    TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+		CREATE INDEX comments_post_parent ON comments (post_id, parent_id, id)`,
+	},
+	{
+		Version: 3,
+		Name:    "add post timestamps",
+		SQL: `ALTER TABLE posts ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+		ALTER TABLE posts ADD COLUMN updated_at TEXT NOT NULL DEFAULT '';
+		UPDATE posts SET
+			created_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+			updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')`,
+	},
+	{
+		Version: 4,
+		Name:    "add post version",
+		SQL:     `ALTER TABLE posts ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	},
+	{
+		Version: 5,
+		Name:    "create users",
+		SQL: `CREATE TABLE users (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			username      TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			password_hash TEXT NOT NULL,
+			created_at    TEXT NOT NULL
+		);
+		ALTER TABLE posts ADD COLUMN author_id INTEGER REFERENCES users(id)`,
+	},
+	{
+		Version: 6,
+		Name:    "add user role",
+		SQL:     `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member'`,
+	},
+	{
+		Version: 7,
+		Name:    "add poster name and id",
+		SQL: `ALTER TABLE posts ADD COLUMN name TEXT NOT NULL DEFAULT '名無しさん';
+		ALTER TABLE posts ADD COLUMN tripcode TEXT NOT NULL DEFAULT '';
+		ALTER TABLE posts ADD COLUMN poster_id TEXT NOT NULL DEFAULT ''`,
+	},
+	{
+		Version: 8,
+		Name:    "create boards",
+		// 既存の投稿はすべて既定の掲示板(ID: 1)に移す
+		SQL: `CREATE TABLE boards (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug            TEXT NOT NULL UNIQUE,
+			name            TEXT NOT NULL,
+			description     TEXT NOT NULL DEFAULT '',
+			max_threads     INTEGER NOT NULL DEFAULT 0,
+			max_post_length INTEGER NOT NULL DEFAULT 0,
+			archived        INTEGER NOT NULL DEFAULT 0,
+			created_at      TEXT NOT NULL
+		);
+		INSERT INTO boards (id, slug, name, created_at) VALUES (1, 'general', '総合', '2024-10-01T09:00:00Z');
+		ALTER TABLE posts ADD COLUMN board_id INTEGER REFERENCES boards(id);
+		UPDATE posts SET board_id = 1;
+		CREATE INDEX posts_board ON posts (board_id, id)`,
+	},
+	{
+		Version: 9,
+		Name:    "add thread bumping",
+		// last_bumped_at は並び替えに使うため固定長の形式で保存する(既存の投稿は作成日時とする)
+		SQL: `ALTER TABLE posts ADD COLUMN last_bumped_at TEXT NOT NULL DEFAULT '';
+		UPDATE posts SET last_bumped_at = strftime('%Y-%m-%dT%H:%M:%f', created_at) || '000000Z';
+		CREATE INDEX posts_bump ON posts (board_id, last_bumped_at, id);
+		ALTER TABLE comments ADD COLUMN sage INTEGER NOT NULL DEFAULT 0`,
+	},
+	{
+		Version: 10,
+		Name:    "add thread archiving",
+		// archived_at が NULL のスレッドはアーカイブされていない
+		SQL: `ALTER TABLE boards ADD COLUMN max_replies INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE posts ADD COLUMN locked INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE posts ADD COLUMN archived_at TEXT`,
+	},
+	{
+		Version: 11,
+		Name:    "use sortable post timestamps",
+		// 作成日時・更新日時による絞り込みと並び替えのため、last_bumped_at と同じ固定長の形式にそろえる
+		SQL: `UPDATE posts SET
+			created_at = strftime('%Y-%m-%dT%H:%M:%f', created_at) || '000000Z',
+			updated_at = strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z';
+		UPDATE posts SET archived_at = strftime('%Y-%m-%dT%H:%M:%f', archived_at) || '000000Z' WHERE archived_at IS NOT NULL`,
+	},
+	{
+		Version: 12,
+		Name:    "add soft delete",
+		// deleted_at が NULL の投稿は削除されていない
+		SQL: `ALTER TABLE posts ADD COLUMN deleted_at TEXT;
+		ALTER TABLE posts ADD COLUMN deleted_by INTEGER REFERENCES users(id);
+		CREATE INDEX posts_deleted ON posts (deleted_at)`,
+	},
+	{
+		Version: 13,
+		Name:    "create post revisions",
+		// 既存の投稿は現在の内容だけを版として登録する(編集者は最初の版の場合のみ投稿者とする)
+		SQL: `CREATE TABLE post_revisions (
+			post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			number     INTEGER NOT NULL,
+			title      TEXT NOT NULL,
+			content    TEXT NOT NULL,
+			editor_id  INTEGER REFERENCES users(id),
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (post_id, number)
+		);
+		INSERT INTO post_revisions (post_id, number, title, content, editor_id, created_at)
+			SELECT id, version, title, content, CASE WHEN version = 1 THEN author_id END, updated_at FROM posts`,
+	},
+	{
+		Version: 14,
+		Name:    "add post content format",
+		SQL:     `ALTER TABLE posts ADD COLUMN content_format TEXT NOT NULL DEFAULT 'plain'`,
+	},
+	{
+		Version: 15,
+		Name:    "create attachments",
+		SQL: `CREATE TABLE attachments (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id      INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			blob_key     TEXT NOT NULL,
+			filename     TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size         INTEGER NOT NULL,
+			created_at   TEXT NOT NULL
+		);
+		CREATE INDEX attachments_post ON attachments (post_id)`,
+	},
+	{
+		Version: 16,
+		Name:    "index attachment blob keys",
+		// 未参照の内容を削除する前に、参照している添付ファイルの有無を確認するため
+		SQL: `CREATE INDEX attachments_blob ON attachments (blob_key)`,
+	},
+	{
+		Version: 17,
+		Name:    "add comment author",
+		// 既存のコメントは匿名(author_id が NULL)とする
+		SQL: `ALTER TABLE comments ADD COLUMN author_id INTEGER REFERENCES users(id)`,
+	},
+}
+
+// 未適用のマイグレーションを順に適用する
+// 適用前のバージョン番号を返す
+func migrate(ctx context.Context, db *sql.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if latest := migrations[len(migrations)-1].Version; current > latest {
+		return 0, fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return 0, err
+		}
+	}
+	return current, nil
+}
+
+// マイグレーションを1件、トランザクション内で適用する
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
@@ -1,30 +1,74 @@
 /*
 * モデル層
-* データベースとのやり取りを行う(簡易的にサンプルデータを使用)
+* データベースとのやり取りを行う(保存先は PostStore で抽象化する)
 * データ構造体を定義する
  */
 
 package models
 
+import "time"
+
 // 投稿データ構造体を定義する
 // 「タグ」機能を用いることで、構造体のフィールドとJSONデータの間で変換を行う
 type Post struct {
-	ID      int    `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID           int           `json:"id"`
+	Title        string        `json:"title"`
+	Content      string        `json:"content"`
+	Format       ContentFormat `json:"content_format"` // 本文の書式
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	LastBumpedAt time.Time     `json:"last_bumped_at"` // 最後にスレッドが上げられた日時(作成日時、またはsageでない返信の日時)
+	Version      int           `json:"version"`        // 更新のたびに1ずつ増える(楽観的排他制御に使用する)
+	AuthorID     int           `json:"author_id"`      // 投稿したユーザーのID(匿名投稿の場合は0)
+	BoardID      int           `json:"board_id"`       // 投稿(スレッド)が属する掲示板のID
+	Name         string        `json:"name"`           // 名前欄の表示名
+	Tripcode     string        `json:"tripcode"`       // トリップ(指定されていない場合は空)
+	PosterID     string        `json:"poster_id"`      // 接続元と日付から求めたID(IPアドレスそのものは保存しない)
+	Locked       bool          `json:"locked"`         // 返信数の上限に達し、返信できないスレッド
+	ArchivedAt   time.Time     `json:"archived_at"`    // スレッドがアーカイブされた日時(アーカイブされていない場合はゼロ値)
+	DeletedAt    time.Time     `json:"deleted_at"`     // 削除(ゴミ箱に移動)された日時(削除されていない場合はゼロ値)
+	DeletedBy    int           `json:"deleted_by"`     // 削除したユーザーのID
+}
+
+// 投稿本文の書式
+type ContentFormat string
+
+const (
+	FormatPlain    ContentFormat = "plain"    // プレーンテキスト
+	FormatMarkdown ContentFormat = "markdown" // Markdown(CommonMark)
+)
+
+// スレッドがアーカイブ済みかを判定する
+// アーカイブ済みのスレッドは閲覧できるが、返信できない
+func (p *Post) Archived() bool {
+	return !p.ArchivedAt.IsZero()
+}
+
+// 投稿が削除済み(ゴミ箱に移動済み)かを判定する
+// 削除済みの投稿は完全に削除されるまで復元できる
+func (p *Post) Deleted() bool {
+	return !p.DeletedAt.IsZero()
+}
+
+// 名前欄が空の場合の表示名
+const DefaultPosterName = "名無しさん"
+
+// サンプルデータの投稿日時(IDごとに1分ずつずらす)
+func seedTime(id int) time.Time {
+	return time.Date(2024, 10, 1, 9, id, 0, 0, time.UTC)
 }
 
 // サンプルデータ
-// 本来はデータベースから取得するが、ここでは簡易的にサンプルデータを使用
-var Posts = []Post{
-	{ID: 1, Title: "投稿1", Content: "サンプル投稿1"},
-	{ID: 2, Title: "投稿2", Content: "サンプル投稿2"},
-	{ID: 3, Title: "投稿3", Content: "サンプル投稿3"},
-	{ID: 4, Title: "投稿4", Content: "サンプル投稿4"},
-	{ID: 5, Title: "投稿5", Content: "サンプル投稿5"},
-	{ID: 6, Title: "投稿6", Content: "サンプル投稿6"},
-	{ID: 7, Title: "投稿7", Content: "サンプル投稿7"},
-	{ID: 8, Title: "投稿8", Content: "サンプル投稿8"},
-	{ID: 9, Title: "投稿9", Content: "サンプル投稿9"},
-	{ID: 10, Title: "投稿10", Content: "サンプル投稿10"},
+// ストアの初期データとして使用する
+var SeedPosts = []Post{
+	{ID: 1, Title: "投稿1", Content: "サンプル投稿1", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(1), UpdatedAt: seedTime(1), LastBumpedAt: seedTime(1)},
+	{ID: 2, Title: "投稿2", Content: "サンプル投稿2", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(2), UpdatedAt: seedTime(2), LastBumpedAt: seedTime(2)},
+	{ID: 3, Title: "投稿3", Content: "サンプル投稿3", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(3), UpdatedAt: seedTime(3), LastBumpedAt: seedTime(3)},
+	{ID: 4, Title: "投稿4", Content: "サンプル投稿4", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(4), UpdatedAt: seedTime(4), LastBumpedAt: seedTime(4)},
+	{ID: 5, Title: "投稿5", Content: "サンプル投稿5", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(5), UpdatedAt: seedTime(5), LastBumpedAt: seedTime(5)},
+	{ID: 6, Title: "投稿6", Content: "サンプル投稿6", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(6), UpdatedAt: seedTime(6), LastBumpedAt: seedTime(6)},
+	{ID: 7, Title: "投稿7", Content: "サンプル投稿7", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(7), UpdatedAt: seedTime(7), LastBumpedAt: seedTime(7)},
+	{ID: 8, Title: "投稿8", Content: "サンプル投稿8", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(8), UpdatedAt: seedTime(8), LastBumpedAt: seedTime(8)},
+	{ID: 9, Title: "投稿9", Content: "サンプル投稿9", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(9), UpdatedAt: seedTime(9), LastBumpedAt: seedTime(9)},
+	{ID: 10, Title: "投稿10", Content: "サンプル投稿10", BoardID: DefaultBoardID, Name: DefaultPosterName, CreatedAt: seedTime(10), UpdatedAt: seedTime(10), LastBumpedAt: seedTime(10)},
 }
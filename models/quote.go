@@ -0,0 +1,7 @@
+package models
+
+// アンカーによる投稿の参照元
+type Backlink struct {
+	Post    Post     // 参照元の投稿(コメントの場合はコメントが属する投稿)
+	Comment *Comment // 参照元のコメント(投稿の本文で参照している場合はnil)
+}
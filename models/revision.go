@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// 投稿の版(リビジョン)
+// 投稿の作成・更新のたびに作成し、以後は変更しない
+type Revision struct {
+	PostID    int       `json:"post_id"`
+	Number    int       `json:"number"` // 版番号(その時点の投稿のVersionと同じ)
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	EditorID  int       `json:"editor_id"` // 作成・編集したユーザーのID(匿名・不明の場合は0)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 投稿の現在の内容から版を作成する
+func newRevision(post *Post, editorID int, at time.Time) Revision {
+	return Revision{
+		PostID:    post.ID,
+		Number:    post.Version,
+		Title:     post.Title,
+		Content:   post.Content,
+		EditorID:  editorID,
+		CreatedAt: at,
+	}
+}
+
+// 版の取得条件
+type RevisionRange struct {
+	PostID      int // 対象の投稿ID
+	AfterNumber int // この版番号より大きい版に限定する(0の場合は制限なし)
+	Limit       int // 取得する最大件数
+}
+
+// 2つの版のタイトルと本文の差分(unified diff形式。変更がない場合は空文字列)
+type RevisionDiff struct {
+	Title   string
+	Content string
+}
+
+// 版fromから版toへの行単位の差分を作成する
+func DiffRevisions(from *Revision, to *Revision) (RevisionDiff, error) {
+	title, err := unifiedDiff(from, to, from.Title, to.Title)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	content, err := unifiedDiff(from, to, from.Content, to.Content)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	return RevisionDiff{Title: title, Content: content}, nil
+}
+
+// 1項目の行単位のunified diffを作成する
+func unifiedDiff(from *Revision, to *Revision, a string, b string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: fmt.Sprintf("revision %d", from.Number),
+		ToFile:   fmt.Sprintf("revision %d", to.Number),
+		Context:  3,
+	})
+	if err != nil {
+		return "", InternalServerError("failed to diff revisions", err.Error())
+	}
+	return diff, nil
+}
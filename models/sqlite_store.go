@@ -0,0 +1,1378 @@
+/*
+* SQLiteのストア実装
+* cgoを使わないpure-Goのドライバ(modernc.org/sqlite)を使用する
+ */
+
+package models
+
+import (
+	"bbs-gql-project/anchor"
+	"bbs-gql-project/search"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteに投稿・コメント・ユーザーデータを保存するストア
+// 全文検索用のインデックスとアンカーによる参照の索引はメモリ上に持ち、データベースを開くときに作成する
+type SQLiteStore struct {
+	db *sql.DB
+	// 索引を更新する書き込みを直列化する
+	// コミットと索引の更新の間に他の書き込みが入ると、索引がコミットと異なる順序で更新されるため
+	mu     sync.Mutex
+	index  *search.Index
+	quotes *anchor.Index
+}
+
+// SQLiteのデータベースを開き、マイグレーションを適用する
+// 新規作成したデータベースにはサンプルデータを投入する
+func OpenSQLiteStore(ctx context.Context, path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	// SQLiteは書き込みを直列化するため、接続を1本に制限する
+	db.SetMaxOpenConns(1)
+
+	previous, err := migrate(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: db, index: search.NewIndex(), quotes: anchor.NewIndex()}
+	if previous == 0 {
+		if err := s.seed(ctx, SeedPosts); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if err := s.buildIndex(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.buildQuoteIndex(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// 保存されている削除済みでない投稿を全文検索用のインデックスに登録する
+func (s *SQLiteStore) buildIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, title, content FROM posts WHERE deleted_at IS NULL`)
+	if err != nil {
+		return fmt.Errorf("build search index: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var title, content string
+		if err := rows.Scan(&id, &title, &content); err != nil {
+			return fmt.Errorf("build search index: %w", err)
+		}
+		s.index.Add(id, title, content)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("build search index: %w", err)
+	}
+	return nil
+}
+
+// 保存されている投稿とコメントの本文をアンカーによる参照の索引に登録する
+// 削除済みの投稿も元に戻せるため登録し、取得時に除外する
+func (s *SQLiteStore) buildQuoteIndex(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, 0, content FROM posts UNION ALL SELECT post_id, id, content FROM comments`)
+	if err != nil {
+		return fmt.Errorf("build quote index: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source anchor.Source
+		var content string
+		if err := rows.Scan(&source.PostID, &source.CommentID, &content); err != nil {
+			return fmt.Errorf("build quote index: %w", err)
+		}
+		s.quotes.Set(source, content)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("build quote index: %w", err)
+	}
+	return nil
+}
+
+// 外部キー制約を有効にした接続文字列を作成する
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=foreign_keys(1)"
+}
+
+// データベースを閉じる
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// 投稿データの保存先を返す
+func (s *SQLiteStore) Posts() PostStore {
+	return (*sqlitePostStore)(s)
+}
+
+// コメントデータの保存先を返す
+func (s *SQLiteStore) Comments() CommentStore {
+	return (*sqliteCommentStore)(s)
+}
+
+// ユーザーデータの保存先を返す
+func (s *SQLiteStore) Users() UserStore {
+	return (*sqliteUserStore)(s)
+}
+
+// 掲示板データの保存先を返す
+func (s *SQLiteStore) Boards() BoardStore {
+	return (*sqliteBoardStore)(s)
+}
+
+// 投稿の版の保存先を返す
+func (s *SQLiteStore) Revisions() RevisionStore {
+	return (*sqliteRevisionStore)(s)
+}
+
+// アンカーによる参照の取得先を返す
+func (s *SQLiteStore) Quotes() QuoteStore {
+	return (*sqliteQuoteStore)(s)
+}
+
+// 添付ファイルの属性の保存先を返す
+func (s *SQLiteStore) Attachments() AttachmentStore {
+	return (*sqliteAttachmentStore)(s)
+}
+
+// サンプルデータを投入する
+func (s *SQLiteStore) seed(ctx context.Context, posts []Post) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("seed posts: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, post := range posts {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO posts (id, title, content, created_at, updated_at, last_bumped_at, name, board_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			post.ID, post.Title, post.Content, formatTime(post.CreatedAt), formatTime(post.UpdatedAt), formatTime(post.LastBumpedAt),
+			post.Name, post.BoardID); err != nil {
+			return fmt.Errorf("seed posts: %w", err)
+		}
+		post.Version = 1
+		if err := insertRevision(ctx, tx, newRevision(&post, post.AuthorID, post.UpdatedAt)); err != nil {
+			return fmt.Errorf("seed posts: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SQLiteの投稿ストア
+type sqlitePostStore SQLiteStore
+
+// 投稿テーブルの取得カラム
+const postColumns = `id, title, content, content_format, created_at, updated_at, last_bumped_at, version, COALESCE(author_id, 0), board_id, name, tripcode, poster_id,
+	locked, archived_at, deleted_at, COALESCE(deleted_by, 0)`
+
+// 絞り込み条件をWHERE句に変換する
+// lower() はASCIIの英字だけを変換するため、インメモリの実装(containsFold)と結果が一致する
+func scopeClause(scope PostScope) (string, []interface{}) {
+	var createdAfter, createdBefore string
+	if !scope.CreatedAfter.IsZero() {
+		createdAfter = formatTime(scope.CreatedAfter)
+	}
+	if !scope.CreatedBefore.IsZero() {
+		createdBefore = formatTime(scope.CreatedBefore)
+	}
+	var hasReplies sql.NullBool
+	if scope.HasReplies != nil {
+		hasReplies = sql.NullBool{Bool: *scope.HasReplies, Valid: true}
+	}
+
+	return `(deleted_at IS NOT NULL) = ? AND (? = 0 OR board_id = ?) AND (? != ? OR archived_at IS NULL) AND (? != ? OR archived_at IS NOT NULL)
+		AND (? = 0 OR author_id = ?)
+		AND instr(lower(title), lower(?)) > 0 AND instr(lower(content), lower(?)) > 0
+		AND (? = '' OR created_at >= ?) AND (? = '' OR created_at < ?)
+		AND (? IS NULL OR EXISTS(SELECT 1 FROM comments WHERE comments.post_id = posts.id) = ?)`,
+		[]interface{}{
+			scope.Deleted, scope.BoardID, scope.BoardID, scope.Status, LiveThreads, scope.Status, ArchivedThreads,
+			scope.AuthorID, scope.AuthorID,
+			scope.TitleContains, scope.ContentContains,
+			createdAfter, createdAfter, createdBefore, createdBefore,
+			hasReplies, hasReplies,
+		}
+}
+
+// IDを指定して投稿を取得する
+func (s *sqlitePostStore) Get(ctx context.Context, id int) (*Post, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, postNotFound()
+	}
+	return &posts[0], nil
+}
+
+// 絞り込み条件に一致する投稿を並び順に従ってoffset件目からlimit件取得する
+func (s *sqlitePostStore) List(ctx context.Context, p PostPage) ([]Post, error) {
+	if p.Offset < 0 || p.Limit <= 0 {
+		return []Post{}, nil
+	}
+	if err := p.Order.Field.check(); err != nil {
+		return nil, err
+	}
+
+	column := string(p.Order.Field)
+	if column == "" {
+		column = string(OrderByID)
+	}
+	direction := "ASC"
+	if p.Order.Desc {
+		direction = "DESC"
+	}
+	// 並び順の項目は確認済みのカラム名のため、クエリに直接埋め込める
+	where, args := scopeClause(p.PostScope)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts WHERE `+where+`
+		ORDER BY `+column+` `+direction+`, id `+direction+` LIMIT ? OFFSET ?`,
+		append(args, p.Limit, p.Offset)...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanPosts(rows)
+}
+
+// 並び順の位置を指定して投稿を取得する
+func (s *sqlitePostStore) Range(ctx context.Context, r PostRange) ([]Post, error) {
+	if err := r.Order.Field.check(); err != nil {
+		return nil, err
+	}
+
+	column := string(r.Order.Field)
+	if column == "" {
+		column = string(OrderByID)
+	}
+	// 並び順で後に来る値の比較演算子
+	later, earlier := ">", "<"
+	if r.Order.Desc {
+		later, earlier = earlier, later
+	}
+	direction := "ASC"
+	if r.Order.Desc != r.Reverse {
+		direction = "DESC"
+	}
+
+	// 並び順の項目は確認済みのカラム名のため、クエリに直接埋め込める
+	where, args := scopeClause(r.PostScope)
+	if r.After != nil {
+		value := orderValue(r.Order.Field, r.After)
+		where += ` AND (` + column + ` ` + later + ` ? OR (` + column + ` = ? AND id ` + later + ` ?))`
+		args = append(args, value, value, r.After.ID)
+	}
+	if r.Before != nil {
+		value := orderValue(r.Order.Field, r.Before)
+		where += ` AND (` + column + ` ` + earlier + ` ? OR (` + column + ` = ? AND id ` + earlier + ` ?))`
+		args = append(args, value, value, r.Before.ID)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts WHERE `+where+`
+		ORDER BY `+column+` `+direction+`, id `+direction+` LIMIT ?`,
+		append(args, r.Limit)...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanPosts(rows)
+}
+
+// 並び順の項目について、データベースに保存されている形式の値を返す
+func orderValue(field PostOrderField, post *Post) any {
+	switch field {
+	case OrderByCreatedAt:
+		return formatTime(post.CreatedAt)
+	case OrderByUpdatedAt:
+		return formatTime(post.UpdatedAt)
+	case OrderByLastBumpedAt:
+		return formatTime(post.LastBumpedAt)
+	case OrderByTitle:
+		return post.Title
+	}
+	return post.ID
+}
+
+// スレッドを上げられた順に投稿を取得する
+func (s *sqlitePostStore) RangeByBump(ctx context.Context, r BumpRange) ([]Post, error) {
+	after := formatTime(r.AfterBumpedAt)
+	where, args := scopeClause(r.PostScope)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts
+		WHERE `+where+`
+		AND (? = 0 OR last_bumped_at < ? OR (last_bumped_at = ? AND id < ?))
+		ORDER BY last_bumped_at DESC, id DESC LIMIT ?`,
+		append(args, r.AfterID, after, after, r.AfterID, r.Limit)...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanPosts(rows)
+}
+
+// 絞り込み条件に一致する投稿の数を取得する
+func (s *sqlitePostStore) Count(ctx context.Context, scope PostScope) (int, error) {
+	return countPosts(ctx, s.db, scope)
+}
+
+// 絞り込み条件に一致する投稿の数を数える
+func countPosts(ctx context.Context, q rowQueryer, scope PostScope) (int, error) {
+	where, args := scopeClause(scope)
+	var count int
+	if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM posts WHERE `+where, args...).Scan(&count); err != nil {
+		return 0, databaseError(err)
+	}
+	return count, nil
+}
+
+// タイトルと本文を全文検索する
+// インデックスで求めたページ内の投稿をデータベースから取得する
+func (s *sqlitePostStore) Search(ctx context.Context, r SearchRange) ([]SearchHit, int, error) {
+	hits := s.index.Search(r.Query)
+	page := r.page(hits)
+	if len(page) == 0 {
+		return []SearchHit{}, len(hits), nil
+	}
+
+	ids := make([]int, len(page))
+	for i, hit := range page {
+		ids[i] = hit.ID
+	}
+	byID, err := (*SQLiteStore)(s).livePostsByID(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]SearchHit, 0, len(page))
+	for _, hit := range page {
+		if post, ok := byID[hit.ID]; ok {
+			result = append(result, SearchHit{Post: post, Score: hit.Score})
+		}
+	}
+	return result, len(hits), nil
+}
+
+// 投稿を新規作成する
+func (s *sqlitePostStore) Create(ctx context.Context, post *Post, attachments []Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	board, err := getBoard(ctx, tx, `id = ?`, post.BoardID)
+	if err != nil {
+		return err
+	}
+	if err := checkNewThread(board); err != nil {
+		return err
+	}
+	live, err := countPosts(ctx, tx, PostScope{BoardID: post.BoardID, Status: LiveThreads})
+	if err != nil {
+		return err
+	}
+	if n := threadsToArchive(board, live); n > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE posts SET archived_at = ? WHERE id IN (
+			SELECT id FROM posts WHERE board_id = ? AND archived_at IS NULL AND deleted_at IS NULL ORDER BY last_bumped_at, id LIMIT ?)`,
+			formatTime(post.CreatedAt), post.BoardID, n); err != nil {
+			return databaseError(err)
+		}
+	}
+
+	post.LastBumpedAt = post.CreatedAt
+	result, err := tx.ExecContext(ctx, `INSERT INTO posts (title, content, content_format, created_at, updated_at, last_bumped_at, author_id, board_id, name, tripcode, poster_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		post.Title, post.Content, post.Format, formatTime(post.CreatedAt), formatTime(post.UpdatedAt), formatTime(post.LastBumpedAt),
+		nullableID(post.AuthorID), post.BoardID, post.Name, post.Tripcode, post.PosterID)
+	if err != nil {
+		return databaseError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return databaseError(err)
+	}
+	post.ID = int(id)
+	post.Version = 1
+	if err := insertRevision(ctx, tx, newRevision(post, post.AuthorID, post.CreatedAt)); err != nil {
+		return err
+	}
+	for i := range attachments {
+		attachments[i].PostID = post.ID
+		if err := insertAttachment(ctx, tx, &attachments[i]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	s.index.Add(post.ID, post.Title, post.Content)
+	s.quotes.Set(anchor.Source{PostID: post.ID}, post.Content)
+	return nil
+}
+
+// 投稿を更新する
+// バージョンが一致する場合だけ更新し、バージョンを1増やして新しい版を作成する
+func (s *sqlitePostStore) Update(ctx context.Context, post *Post, editorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE posts SET title = ?, content = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL`,
+		post.Title, post.Content, formatTime(post.UpdatedAt), post.ID, post.Version)
+	if err != nil {
+		return databaseError(err)
+	}
+	if err := checkVersion(ctx, tx, result, post.ID); err != nil {
+		return err
+	}
+	post.Version++
+	if err := insertRevision(ctx, tx, newRevision(post, editorID, post.UpdatedAt)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	s.index.Add(post.ID, post.Title, post.Content)
+	s.quotes.Set(anchor.Source{PostID: post.ID}, post.Content)
+	return nil
+}
+
+// IDを指定して投稿を削除済みにする
+func (s *sqlitePostStore) Delete(ctx context.Context, id int, expectedVersion int, deletedBy int, deletedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE posts SET deleted_at = ?, deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		formatTime(deletedAt), nullableID(deletedBy), id, expectedVersion, expectedVersion)
+	if err != nil {
+		return databaseError(err)
+	}
+	if err := checkVersion(ctx, s.db, result, id); err != nil {
+		return err
+	}
+	s.index.Remove(id)
+	return nil
+}
+
+// 削除済みの投稿を元に戻す
+func (s *sqlitePostStore) Restore(ctx context.Context, id int) (*Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE posts SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	if err := requireAffected(result, deletedPostNotFound); err != nil {
+		return nil, err
+	}
+	post, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.index.Add(post.ID, post.Title, post.Content)
+	return post, nil
+}
+
+// olderThanより前に削除された投稿を完全に削除する
+// コメント・版・添付ファイルの属性は外部キー制約(ON DELETE CASCADE)により削除される
+func (s *sqlitePostStore) Purge(ctx context.Context, olderThan time.Time) (int, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, databaseError(err)
+	}
+	defer tx.Rollback()
+
+	// 添付ファイルの属性は投稿とともに削除されるため、先に内容のキーを取得する
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT a.blob_key FROM attachments a JOIN posts p ON p.id = a.post_id
+		WHERE p.deleted_at IS NOT NULL AND p.deleted_at < ?`, formatTime(olderThan))
+	if err != nil {
+		return 0, nil, databaseError(err)
+	}
+	blobKeys, err := scanStrings(rows)
+	if err != nil {
+		return 0, nil, err
+	}
+	rows, err = tx.QueryContext(ctx, `DELETE FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < ? RETURNING id`, formatTime(olderThan))
+	if err != nil {
+		return 0, nil, databaseError(err)
+	}
+	purged, err := scanIDs(rows)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, databaseError(err)
+	}
+	for _, id := range purged {
+		s.quotes.RemovePost(id)
+	}
+	return len(purged), blobKeys, nil
+}
+
+// バージョンを条件にした更新・削除の結果を確認する
+// 対象の行がない場合は、投稿が存在しないかバージョンが一致しなかったかを判定する
+func checkVersion(ctx context.Context, q rowQueryer, result sql.Result, id int) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return databaseError(err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var current int
+	err = q.QueryRowContext(ctx, `SELECT version FROM posts WHERE id = ? AND deleted_at IS NULL`, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return postNotFound()
+	}
+	if err != nil {
+		return databaseError(err)
+	}
+	return VersionConflictError(current)
+}
+
+// クエリ結果を投稿のスライスに変換する
+func scanPosts(rows *sql.Rows) ([]Post, error) {
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var post Post
+		var createdAt, updatedAt, lastBumpedAt string
+		var archivedAt, deletedAt sql.NullString
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Format, &createdAt, &updatedAt, &lastBumpedAt, &post.Version, &post.AuthorID,
+			&post.BoardID, &post.Name, &post.Tripcode, &post.PosterID, &post.Locked, &archivedAt, &deletedAt, &post.DeletedBy); err != nil {
+			return nil, databaseError(err)
+		}
+		var err error
+		if post.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		if post.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, databaseError(err)
+		}
+		if post.LastBumpedAt, err = parseTime(lastBumpedAt); err != nil {
+			return nil, databaseError(err)
+		}
+		if archivedAt.Valid {
+			if post.ArchivedAt, err = parseTime(archivedAt.String); err != nil {
+				return nil, databaseError(err)
+			}
+		}
+		if deletedAt.Valid {
+			if post.DeletedAt, err = parseTime(deletedAt.String); err != nil {
+				return nil, databaseError(err)
+			}
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return posts, nil
+}
+
+// SQLiteのコメントストア
+type sqliteCommentStore SQLiteStore
+
+// コメントテーブルの取得カラム
+const commentColumns = `id, post_id, COALESCE(parent_id, 0), content, sage, COALESCE(author_id, 0), created_at`
+
+// 削除済みでない投稿へのコメントに限定する条件
+const liveCommentClause = `post_id IN (SELECT id FROM posts WHERE deleted_at IS NULL)`
+
+// IDを指定してコメントを取得する
+func (s *sqliteCommentStore) Get(ctx context.Context, id int) (*Comment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = ? AND `+liveCommentClause, id)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, commentNotFound()
+	}
+	return &comments[0], nil
+}
+
+// 投稿または親コメントを指定してコメントを取得する
+func (s *sqliteCommentStore) Range(ctx context.Context, r CommentRange) ([]Comment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+commentColumns+` FROM comments
+		WHERE post_id = ? AND COALESCE(parent_id, 0) = ? AND id > ?
+		ORDER BY id LIMIT ?`,
+		r.PostID, r.ParentID, r.AfterID, r.Limit)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanComments(rows)
+}
+
+// 投稿または親コメントを指定してコメント数を取得する
+func (s *sqliteCommentStore) Count(ctx context.Context, postID int, parentID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE post_id = ? AND COALESCE(parent_id, 0) = ?`, postID, parentID).
+		Scan(&count)
+	if err != nil {
+		return 0, databaseError(err)
+	}
+	return count, nil
+}
+
+// コメントを新規作成する
+func (s *sqliteCommentStore) Create(ctx context.Context, comment *Comment, bumpLimit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	var post Post
+	var board Board
+	var archivedAt sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT p.locked, p.archived_at, b.max_replies
+		FROM posts p JOIN boards b ON b.id = p.board_id WHERE p.id = ? AND p.deleted_at IS NULL`, comment.PostID).
+		Scan(&post.Locked, &archivedAt, &board.MaxReplies)
+	if errors.Is(err, sql.ErrNoRows) {
+		return postNotFound()
+	}
+	if err != nil {
+		return databaseError(err)
+	}
+	if archivedAt.Valid {
+		if post.ArchivedAt, err = parseTime(archivedAt.String); err != nil {
+			return databaseError(err)
+		}
+	}
+
+	if comment.ParentID != 0 {
+		var parentPostID int
+		err := tx.QueryRowContext(ctx, `SELECT post_id FROM comments WHERE id = ?`, comment.ParentID).Scan(&parentPostID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return commentNotFound()
+		}
+		if err != nil {
+			return databaseError(err)
+		}
+		if parentPostID != comment.PostID {
+			return parentMismatch()
+		}
+	}
+
+	var replies int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE post_id = ?`, comment.PostID).Scan(&replies); err != nil {
+		return databaseError(err)
+	}
+	if err := checkReply(&post, &board, replies); err != nil {
+		return err
+	}
+	if bumps(comment, replies, bumpLimit) {
+		if _, err := tx.ExecContext(ctx, `UPDATE posts SET last_bumped_at = ? WHERE id = ?`,
+			formatTime(comment.CreatedAt), comment.PostID); err != nil {
+			return databaseError(err)
+		}
+	}
+	if locks(&board, replies+1) {
+		if _, err := tx.ExecContext(ctx, `UPDATE posts SET locked = 1 WHERE id = ?`, comment.PostID); err != nil {
+			return databaseError(err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO comments (post_id, parent_id, content, sage, author_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		comment.PostID, nullableID(comment.ParentID), comment.Content, comment.Sage, nullableID(comment.AuthorID), formatTime(comment.CreatedAt))
+	if err != nil {
+		return databaseError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return databaseError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	comment.ID = int(id)
+	s.quotes.Set(anchor.Source{PostID: comment.PostID, CommentID: comment.ID}, comment.Content)
+	return nil
+}
+
+// コメントの本文を更新する
+func (s *sqliteCommentStore) Update(ctx context.Context, comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	err = tx.QueryRowContext(ctx, `SELECT p.archived_at IS NOT NULL FROM comments c JOIN posts p ON p.id = c.post_id
+		WHERE c.id = ? AND p.deleted_at IS NULL`, comment.ID).Scan(&archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return commentNotFound()
+	}
+	if err != nil {
+		return databaseError(err)
+	}
+	if archived {
+		return ThreadArchivedError()
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE comments SET content = ? WHERE id = ?`, comment.Content, comment.ID); err != nil {
+		return databaseError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	s.quotes.Set(anchor.Source{PostID: comment.PostID, CommentID: comment.ID}, comment.Content)
+	return nil
+}
+
+// IDを指定してコメントを削除する
+// 返信は外部キー制約(ON DELETE CASCADE)により削除されるため、削除前に返信も含めて参照の索引から除く対象を求める
+func (s *sqliteCommentStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `WITH RECURSIVE tree(id, post_id) AS (
+			SELECT id, post_id FROM comments WHERE id = ? AND `+liveCommentClause+`
+			UNION ALL SELECT c.id, c.post_id FROM comments c JOIN tree t ON c.parent_id = t.id)
+		SELECT post_id, id FROM tree`, id)
+	if err != nil {
+		return databaseError(err)
+	}
+	sources, err := scanSources(rows)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return commentNotFound()
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE id = ?`, id); err != nil {
+		return databaseError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	for _, source := range sources {
+		s.quotes.Remove(source)
+	}
+	return nil
+}
+
+// クエリ結果をコメントのスライスに変換する
+func scanComments(rows *sql.Rows) ([]Comment, error) {
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		var createdAt string
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Content, &comment.Sage, &comment.AuthorID, &createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		t, err := parseTime(createdAt)
+		if err != nil {
+			return nil, databaseError(err)
+		}
+		comment.CreatedAt = t
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return comments, nil
+}
+
+// SQLiteのアンカーの参照ストア
+type sqliteQuoteStore SQLiteStore
+
+// 投稿の本文が参照している投稿をIDの昇順で取得する
+func (s *sqliteQuoteStore) Quoted(ctx context.Context, postID int) ([]Post, error) {
+	ids := s.quotes.Targets(postID)
+	byID, err := (*SQLiteStore)(s).livePostsByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := []Post{}
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			result = append(result, post)
+		}
+	}
+	return result, nil
+}
+
+// 投稿を参照している投稿・コメントを取得する
+func (s *sqliteQuoteStore) QuotedBy(ctx context.Context, postID int) ([]Backlink, error) {
+	sources := s.quotes.Sources(postID)
+	var postIDs, commentIDs []int
+	for _, source := range sources {
+		postIDs = append(postIDs, source.PostID)
+		if source.CommentID != 0 {
+			commentIDs = append(commentIDs, source.CommentID)
+		}
+	}
+	posts, err := (*SQLiteStore)(s).livePostsByID(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := (*SQLiteStore)(s).liveCommentsByID(ctx, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Backlink{}
+	for _, source := range sources {
+		post, ok := posts[source.PostID]
+		if !ok {
+			continue
+		}
+		backlink := Backlink{Post: post}
+		if source.CommentID != 0 {
+			comment, ok := comments[source.CommentID]
+			if !ok {
+				continue
+			}
+			backlink.Comment = &comment
+		}
+		result = append(result, backlink)
+	}
+	return result, nil
+}
+
+// IDを指定して削除済みでない投稿をまとめて取得する
+func (s *SQLiteStore) livePostsByID(ctx context.Context, ids []int) (map[int]Post, error) {
+	byID := make(map[int]Post, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	placeholders, args := inClause(ids)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+postColumns+` FROM posts WHERE id IN (`+placeholders+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	return byID, nil
+}
+
+// IDを指定して削除済みでない投稿へのコメントをまとめて取得する
+func (s *SQLiteStore) liveCommentsByID(ctx context.Context, ids []int) (map[int]Comment, error) {
+	byID := make(map[int]Comment, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	placeholders, args := inClause(ids)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id IN (`+placeholders+`) AND `+liveCommentClause, args...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+	return byID, nil
+}
+
+// IN句のプレースホルダと引数を作成する
+func inClause(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// クエリ結果をIDのスライスに変換する
+func scanIDs(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, databaseError(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return ids, nil
+}
+
+// クエリ結果を文字列のスライスに変換する
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, databaseError(err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return values, nil
+}
+
+// クエリ結果(投稿ID、コメントID)をアンカーの参照元のスライスに変換する
+func scanSources(rows *sql.Rows) ([]anchor.Source, error) {
+	defer rows.Close()
+
+	sources := []anchor.Source{}
+	for rows.Next() {
+		var source anchor.Source
+		if err := rows.Scan(&source.PostID, &source.CommentID); err != nil {
+			return nil, databaseError(err)
+		}
+		sources = append(sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return sources, nil
+}
+
+// SQLiteの添付ファイルストア
+type sqliteAttachmentStore SQLiteStore
+
+// 添付ファイルテーブルの取得カラム
+const attachmentColumns = `id, post_id, blob_key, filename, content_type, size, created_at`
+
+// 添付ファイルの属性を保存し、IDを設定する
+func insertAttachment(ctx context.Context, tx *sql.Tx, attachment *Attachment) error {
+	result, err := tx.ExecContext(ctx, `INSERT INTO attachments (post_id, blob_key, filename, content_type, size, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		attachment.PostID, attachment.BlobKey, attachment.Filename, attachment.ContentType, attachment.Size, formatTime(attachment.CreatedAt))
+	if err != nil {
+		return databaseError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return databaseError(err)
+	}
+	attachment.ID = int(id)
+	return nil
+}
+
+// IDを指定して添付ファイルを取得する
+func (s *sqliteAttachmentStore) Get(ctx context.Context, id int) (*Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments
+		WHERE id = ? AND post_id IN (SELECT id FROM posts WHERE deleted_at IS NULL)`, id)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, attachmentNotFound()
+	}
+	return &attachments[0], nil
+}
+
+// 投稿の添付ファイルをIDの昇順で取得する
+func (s *sqliteAttachmentStore) List(ctx context.Context, postID int) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE post_id = ? ORDER BY id`, postID)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanAttachments(rows)
+}
+
+// 内容のキーを参照している添付ファイルがあるか
+func (s *sqliteAttachmentStore) Referenced(ctx context.Context, blobKey string) (bool, error) {
+	var referenced bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM attachments WHERE blob_key = ?)`, blobKey).Scan(&referenced); err != nil {
+		return false, databaseError(err)
+	}
+	return referenced, nil
+}
+
+// クエリ結果を添付ファイルのスライスに変換する
+func scanAttachments(rows *sql.Rows) ([]Attachment, error) {
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		var createdAt string
+		if err := rows.Scan(&attachment.ID, &attachment.PostID, &attachment.BlobKey, &attachment.Filename,
+			&attachment.ContentType, &attachment.Size, &createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		t, err := parseTime(createdAt)
+		if err != nil {
+			return nil, databaseError(err)
+		}
+		attachment.CreatedAt = t
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return attachments, nil
+}
+
+// SQLiteの版ストア
+type sqliteRevisionStore SQLiteStore
+
+// 版テーブルの取得カラム
+const revisionColumns = `post_id, number, title, content, COALESCE(editor_id, 0), created_at`
+
+// 版を保存する
+func insertRevision(ctx context.Context, tx *sql.Tx, revision Revision) error {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO post_revisions (post_id, number, title, content, editor_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		revision.PostID, revision.Number, revision.Title, revision.Content, nullableID(revision.EditorID), formatTime(revision.CreatedAt)); err != nil {
+		return databaseError(err)
+	}
+	return nil
+}
+
+// 投稿IDと版番号を指定して版を取得する
+func (s *sqliteRevisionStore) Get(ctx context.Context, postID int, number int) (*Revision, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = ? AND deleted_at IS NULL)`, postID).Scan(&exists); err != nil {
+		return nil, databaseError(err)
+	}
+	if !exists {
+		return nil, postNotFound()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+revisionColumns+` FROM post_revisions WHERE post_id = ? AND number = ?`, postID, number)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	revisions, err := scanRevisions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, revisionNotFound()
+	}
+	return &revisions[0], nil
+}
+
+// 投稿の版を版番号の昇順で取得する
+func (s *sqliteRevisionStore) Range(ctx context.Context, r RevisionRange) ([]Revision, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+revisionColumns+` FROM post_revisions
+		WHERE post_id = ? AND number > ? ORDER BY number LIMIT ?`,
+		r.PostID, r.AfterNumber, r.Limit)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	return scanRevisions(rows)
+}
+
+// 投稿の版の数を取得する
+func (s *sqliteRevisionStore) Count(ctx context.Context, postID int) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM post_revisions WHERE post_id = ?`, postID).Scan(&count); err != nil {
+		return 0, databaseError(err)
+	}
+	return count, nil
+}
+
+// クエリ結果を版のスライスに変換する
+func scanRevisions(rows *sql.Rows) ([]Revision, error) {
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		var revision Revision
+		var createdAt string
+		if err := rows.Scan(&revision.PostID, &revision.Number, &revision.Title, &revision.Content, &revision.EditorID, &createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		t, err := parseTime(createdAt)
+		if err != nil {
+			return nil, databaseError(err)
+		}
+		revision.CreatedAt = t
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return revisions, nil
+}
+
+// SQLiteのユーザーストア
+type sqliteUserStore SQLiteStore
+
+// ユーザーテーブルの取得カラム
+const userColumns = `id, username, password_hash, role, created_at`
+
+// IDを指定してユーザーを取得する
+func (s *sqliteUserStore) Get(ctx context.Context, id int) (*User, error) {
+	return s.getWhere(ctx, `id = ?`, id)
+}
+
+// ユーザー名を指定してユーザーを取得する
+// usernameカラムは COLLATE NOCASE のため、大文字・小文字は区別しない
+func (s *sqliteUserStore) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return s.getWhere(ctx, `username = ?`, username)
+}
+
+// 条件に一致するユーザーを1件取得する
+func (s *sqliteUserStore) getWhere(ctx context.Context, where string, arg interface{}) (*User, error) {
+	var user User
+	var createdAt string
+	err := s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+where, arg).
+		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, userNotFound()
+	}
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	if user.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, databaseError(err)
+	}
+	return &user, nil
+}
+
+// ユーザーを新規作成する
+func (s *sqliteUserStore) Create(ctx context.Context, user *User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`, user.Username).Scan(&exists); err != nil {
+		return databaseError(err)
+	}
+	if exists {
+		return usernameTaken()
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO users (username, password_hash, role, created_at) VALUES (?, ?, ?, ?)`,
+		user.Username, user.PasswordHash, user.Role, formatTime(user.CreatedAt))
+	if err != nil {
+		return databaseError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return databaseError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	user.ID = int(id)
+	return nil
+}
+
+// ユーザーの権限を変更する
+func (s *sqliteUserStore) UpdateRole(ctx context.Context, id int, role Role) (*User, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, id)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	if err := requireAffected(result, userNotFound); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// SQLiteの掲示板ストア
+type sqliteBoardStore SQLiteStore
+
+// 掲示板テーブルの取得カラム
+const boardColumns = `id, slug, name, description, max_threads, max_replies, max_post_length, archived, created_at`
+
+// 1行を取得するクエリの実行元(*sql.DB または *sql.Tx)
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// 条件に一致する掲示板を1件取得する
+func getBoard(ctx context.Context, q rowQueryer, where string, arg interface{}) (*Board, error) {
+	var board Board
+	var createdAt string
+	err := q.QueryRowContext(ctx, `SELECT `+boardColumns+` FROM boards WHERE `+where, arg).
+		Scan(&board.ID, &board.Slug, &board.Name, &board.Description, &board.MaxThreads, &board.MaxReplies, &board.MaxPostLength, &board.Archived, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, boardNotFound()
+	}
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	if board.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, databaseError(err)
+	}
+	return &board, nil
+}
+
+// IDを指定して掲示板を取得する
+func (s *sqliteBoardStore) Get(ctx context.Context, id int) (*Board, error) {
+	return getBoard(ctx, s.db, `id = ?`, id)
+}
+
+// スラッグを指定して掲示板を取得する
+func (s *sqliteBoardStore) GetBySlug(ctx context.Context, slug string) (*Board, error) {
+	return getBoard(ctx, s.db, `slug = ?`, slug)
+}
+
+// 掲示板の一覧を取得する
+func (s *sqliteBoardStore) List(ctx context.Context, includeArchived bool) ([]Board, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+boardColumns+` FROM boards WHERE ? OR archived = 0 ORDER BY id`, includeArchived)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer rows.Close()
+
+	boards := []Board{}
+	for rows.Next() {
+		var board Board
+		var createdAt string
+		if err := rows.Scan(&board.ID, &board.Slug, &board.Name, &board.Description, &board.MaxThreads, &board.MaxReplies, &board.MaxPostLength, &board.Archived, &createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		if board.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, databaseError(err)
+		}
+		boards = append(boards, board)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return boards, nil
+}
+
+// 掲示板を新規作成する
+func (s *sqliteBoardStore) Create(ctx context.Context, board *Board) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM boards WHERE slug = ?)`, board.Slug).Scan(&exists); err != nil {
+		return databaseError(err)
+	}
+	if exists {
+		return slugTaken()
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO boards (slug, name, description, max_threads, max_replies, max_post_length, archived, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		board.Slug, board.Name, board.Description, board.MaxThreads, board.MaxReplies, board.MaxPostLength, board.Archived, formatTime(board.CreatedAt))
+	if err != nil {
+		return databaseError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return databaseError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return databaseError(err)
+	}
+	board.ID = int(id)
+	return nil
+}
+
+// 掲示板を更新する
+func (s *sqliteBoardStore) Update(ctx context.Context, board *Board) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE boards SET name = ?, description = ?, max_threads = ?, max_replies = ?, max_post_length = ?, archived = ?
+		WHERE id = ?`,
+		board.Name, board.Description, board.MaxThreads, board.MaxReplies, board.MaxPostLength, board.Archived, board.ID)
+	if err != nil {
+		return databaseError(err)
+	}
+	return requireAffected(result, boardNotFound)
+}
+
+// 0を NULL として扱うIDを変換する
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// 日時をデータベースに保存する文字列に変換する
+// 固定長の形式にするため、文字列の大小が日時の前後と一致する(並び替えや範囲の指定に使う)
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// データベースに保存された文字列を日時に変換する
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// 更新対象の行が存在しない場合に Not Found を返す
+func requireAffected(result sql.Result, notFound func() *AppError) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return databaseError(err)
+	}
+	if n == 0 {
+		return notFound()
+	}
+	return nil
+}
+
+// データベースのエラーを 500 Internal Server Error に変換する
+func databaseError(err error) *AppError {
+	return InternalServerError("database error", err.Error())
+}
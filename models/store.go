@@ -0,0 +1,459 @@
+/*
+* ストア層
+* 投稿データの永続化方法を抽象化する
+ */
+
+package models
+
+import (
+	"bbs-gql-project/search"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 各データの保存先をまとめたインターフェース
+// 投稿の削除時にコメントも削除するため、同じ保存先を共有する
+type Store interface {
+	Posts() PostStore
+	Comments() CommentStore
+	Users() UserStore
+	Boards() BoardStore
+	Revisions() RevisionStore
+	Quotes() QuoteStore
+	Attachments() AttachmentStore
+}
+
+// 投稿データの保存先を表すインターフェース
+// リゾルバはこのインターフェースを通してのみ投稿データにアクセスする
+type PostStore interface {
+	// IDを指定して投稿を取得する(削除済みの投稿は Not Found)
+	Get(ctx context.Context, id int) (*Post, error)
+	// 絞り込み条件に一致する投稿を並び順に従ってoffset件目からlimit件取得する
+	List(ctx context.Context, p PostPage) ([]Post, error)
+	// 並び順の位置を指定して投稿を取得する(カーソルページネーション用)
+	Range(ctx context.Context, r PostRange) ([]Post, error)
+	// スレッドを最後に上げられた順(新しい順)に取得する(カーソルページネーション用)
+	RangeByBump(ctx context.Context, r BumpRange) ([]Post, error)
+	// 絞り込み条件に一致する投稿の数を取得する
+	Count(ctx context.Context, scope PostScope) (int, error)
+	// タイトルと本文を全文検索し、関連度の高い順に取得する(一致した投稿の総数も返す)
+	// 検索用のインデックスは投稿の作成・更新・削除と同時に更新する
+	Search(ctx context.Context, r SearchRange) ([]SearchHit, int, error)
+	// 投稿を新規作成する(IDはストア側で採番する)
+	// 投稿者を編集者とする最初の版と、添付ファイルも作成する(添付ファイルのIDと投稿IDを設定する)
+	// 掲示板がアーカイブ済みの場合は Conflict を返す
+	// 掲示板のスレッド数が上限に達している場合は、最後に上げられたのが最も古いスレッドをアーカイブする
+	Create(ctx context.Context, post *Post, attachments []Attachment) error
+	// 投稿を更新する
+	// post.Versionが保存されているバージョンと異なる場合は Conflict を返す
+	// 更新に成功するとpost.Versionを1増やし、editorIDを編集者とする新しい版を作成する
+	Update(ctx context.Context, post *Post, editorID int) error
+	// IDを指定して投稿を削除済み(ゴミ箱)にする(コメントは完全に削除するまで保持する)
+	// expectedVersionが0以外で、保存されているバージョンと異なる場合は Conflict を返す
+	Delete(ctx context.Context, id int, expectedVersion int, deletedBy int, deletedAt time.Time) error
+	// 削除済みの投稿を元に戻す(削除済みでない場合は Not Found)
+	Restore(ctx context.Context, id int) (*Post, error)
+	// olderThanより前に削除された投稿をコメント・版・添付ファイルの属性とともに完全に削除し、削除した投稿の数を返す
+	// 削除した添付ファイルの内容のキーも返す(内容は他の投稿と共有している場合があるため、BlobStore からは削除しない)
+	Purge(ctx context.Context, olderThan time.Time) (int, []string, error)
+}
+
+// コメントデータの保存先を表すインターフェース
+type CommentStore interface {
+	// IDを指定してコメントを取得する(削除済みの投稿へのコメントは Not Found)
+	Get(ctx context.Context, id int) (*Comment, error)
+	// 投稿または親コメントを指定してコメントをIDの昇順で取得する
+	Range(ctx context.Context, r CommentRange) ([]Comment, error)
+	// 投稿または親コメントを指定してコメント数を取得する
+	Count(ctx context.Context, postID int, parentID int) (int, error)
+	// コメントを新規作成する(投稿と親コメントの存在を確認する)
+	// スレッドがアーカイブ済みの場合は THREAD_ARCHIVED、ロックされている場合は THREAD_LOCKED を返す
+	// sageでないコメントは、投稿へのコメント数がbumpLimit以下(0の場合は無制限)の間スレッドを上げる
+	// 投稿へのコメント数が掲示板の返信数の上限に達すると、スレッドをロックする
+	Create(ctx context.Context, comment *Comment, bumpLimit int) error
+	// コメントの本文を更新する
+	// スレッドがアーカイブ済みの場合は Conflict (THREAD_ARCHIVED) を返す
+	Update(ctx context.Context, comment *Comment) error
+	// IDを指定してコメントを削除する(返信もすべて削除する)
+	Delete(ctx context.Context, id int) error
+}
+
+// ユーザーデータの保存先を表すインターフェース
+type UserStore interface {
+	// IDを指定してユーザーを取得する
+	Get(ctx context.Context, id int) (*User, error)
+	// ユーザー名を指定してユーザーを取得する(大文字・小文字は区別しない)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	// ユーザーを新規作成する(ユーザー名が既に使われている場合は Conflict を返す)
+	Create(ctx context.Context, user *User) error
+	// ユーザーの権限を変更する
+	UpdateRole(ctx context.Context, id int, role Role) (*User, error)
+}
+
+// 投稿の版の保存先を表すインターフェース
+// 版は投稿の作成・更新時に PostStore が作成する
+type RevisionStore interface {
+	// 投稿IDと版番号を指定して版を取得する(削除済みの投稿の版は Not Found)
+	Get(ctx context.Context, postID int, number int) (*Revision, error)
+	// 投稿の版を版番号の昇順で取得する
+	Range(ctx context.Context, r RevisionRange) ([]Revision, error)
+	// 投稿の版の数を取得する
+	Count(ctx context.Context, postID int) (int, error)
+}
+
+// アンカー(>>N)による投稿の参照の取得先を表すインターフェース
+// 参照の索引は投稿・コメントの作成・更新・削除と同時に更新する
+type QuoteStore interface {
+	// 投稿の本文が参照している投稿をIDの昇順で取得する(削除済みの投稿は除く)
+	Quoted(ctx context.Context, postID int) ([]Post, error)
+	// 投稿を参照している投稿・コメントを取得する(削除済みの投稿とそのコメントは除く)
+	QuotedBy(ctx context.Context, postID int) ([]Backlink, error)
+}
+
+// 添付ファイルの属性の保存先を表すインターフェース
+// 添付ファイルは投稿の作成時に PostStore が作成し、内容は BlobStore に保存する
+type AttachmentStore interface {
+	// IDを指定して添付ファイルを取得する(削除済みの投稿の添付ファイルは Not Found)
+	Get(ctx context.Context, id int) (*Attachment, error)
+	// 投稿の添付ファイルをIDの昇順で取得する
+	List(ctx context.Context, postID int) ([]Attachment, error)
+	// 内容のキーを参照している添付ファイルがあるか(削除済みの投稿の添付ファイルも含む)
+	Referenced(ctx context.Context, blobKey string) (bool, error)
+}
+
+// 掲示板データの保存先を表すインターフェース
+type BoardStore interface {
+	// IDを指定して掲示板を取得する
+	Get(ctx context.Context, id int) (*Board, error)
+	// スラッグを指定して掲示板を取得する
+	GetBySlug(ctx context.Context, slug string) (*Board, error)
+	// 掲示板をIDの昇順で取得する(includeArchivedがfalseの場合はアーカイブ済みを除く)
+	List(ctx context.Context, includeArchived bool) ([]Board, error)
+	// 掲示板を新規作成する(スラッグが既に使われている場合は Conflict を返す)
+	Create(ctx context.Context, board *Board) error
+	// 掲示板を更新する(スラッグは変更できない)
+	Update(ctx context.Context, board *Board) error
+}
+
+// スレッドの状態による絞り込み
+type ThreadStatus int
+
+const (
+	AnyThreads      ThreadStatus = iota // 絞り込まない
+	LiveThreads                         // アーカイブされていないスレッドに限定する
+	ArchivedThreads                     // アーカイブ済みのスレッドに限定する
+)
+
+// 投稿の絞り込み条件(一覧の取得と件数の集計で共通)
+// 指定した条件をすべて満たす投稿に限定する
+type PostScope struct {
+	BoardID         int          // 掲示板の投稿に限定する(0の場合は制限なし)
+	Status          ThreadStatus // スレッドの状態
+	AuthorID        int          // 投稿したユーザーの投稿に限定する(0の場合は制限なし)
+	TitleContains   string       // タイトルに含む文字列(英字の大文字・小文字は区別しない。空の場合は制限なし)
+	ContentContains string       // 本文に含む文字列(同上)
+	CreatedAfter    time.Time    // この日時以降に作成された投稿に限定する(ゼロ値の場合は制限なし)
+	CreatedBefore   time.Time    // この日時より前に作成された投稿に限定する(ゼロ値の場合は制限なし)
+	HasReplies      *bool        // コメントの有無(nilの場合は制限なし)
+	Deleted         bool         // trueの場合は削除済みの投稿に限定する(falseの場合は削除済みの投稿を除く)
+}
+
+// 投稿が絞り込み条件に一致するかを判定する
+// hasReplies は投稿にコメントがあるかを返す(HasRepliesが指定されている場合のみ呼び出す)
+func (s PostScope) contains(post *Post, hasReplies func(postID int) bool) bool {
+	if post.Deleted() != s.Deleted {
+		return false
+	}
+	if s.BoardID > 0 && post.BoardID != s.BoardID {
+		return false
+	}
+	switch s.Status {
+	case LiveThreads:
+		if post.Archived() {
+			return false
+		}
+	case ArchivedThreads:
+		if !post.Archived() {
+			return false
+		}
+	}
+	if s.AuthorID > 0 && post.AuthorID != s.AuthorID {
+		return false
+	}
+	if !containsFold(post.Title, s.TitleContains) || !containsFold(post.Content, s.ContentContains) {
+		return false
+	}
+	if !s.CreatedAfter.IsZero() && post.CreatedAt.Before(s.CreatedAfter) {
+		return false
+	}
+	if !s.CreatedBefore.IsZero() && !post.CreatedAt.Before(s.CreatedBefore) {
+		return false
+	}
+	return s.HasReplies == nil || hasReplies(post.ID) == *s.HasReplies
+}
+
+// 英字の大文字・小文字を区別せずに部分文字列を含むかを判定する
+// SQLiteの lower() にそろえるため、ASCII以外の文字は変換しない
+func containsFold(s string, substr string) bool {
+	return strings.Contains(lowerASCII(s), lowerASCII(substr))
+}
+
+// ASCIIの英大文字だけを小文字に変換する
+func lowerASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, s)
+}
+
+// 投稿の並び順の項目(値はデータベースのカラム名)
+type PostOrderField string
+
+const (
+	OrderByID           PostOrderField = "id"
+	OrderByCreatedAt    PostOrderField = "created_at"
+	OrderByUpdatedAt    PostOrderField = "updated_at"
+	OrderByLastBumpedAt PostOrderField = "last_bumped_at"
+	OrderByTitle        PostOrderField = "title"
+)
+
+// 並び順に指定できる項目
+var postOrderFields = []PostOrderField{OrderByID, OrderByCreatedAt, OrderByUpdatedAt, OrderByLastBumpedAt, OrderByTitle}
+
+// 並び順の項目を確認する(空の場合はIDとして扱う)
+func (f PostOrderField) check() error {
+	if f == "" {
+		return nil
+	}
+	for _, field := range postOrderFields {
+		if f == field {
+			return nil
+		}
+	}
+	names := make([]string, len(postOrderFields))
+	for i, field := range postOrderFields {
+		names[i] = string(field)
+	}
+	return BadRequestError("invalid order field", fmt.Sprintf("unknown order field %q (must be one of: %s)", f, strings.Join(names, ", ")))
+}
+
+// 投稿の並び順
+// 項目の値が同じ投稿はIDの順(Descがtrueの場合は降順)に並べる
+type PostOrder struct {
+	Field PostOrderField // 空の場合はID
+	Desc  bool           // trueの場合は降順
+}
+
+// 投稿aが投稿bより前に並ぶかを判定する
+func (o PostOrder) less(a *Post, b *Post) bool {
+	var cmp int
+	switch o.Field {
+	case OrderByCreatedAt:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	case OrderByUpdatedAt:
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	case OrderByLastBumpedAt:
+		cmp = a.LastBumpedAt.Compare(b.LastBumpedAt)
+	case OrderByTitle:
+		cmp = strings.Compare(a.Title, b.Title)
+	}
+	if cmp == 0 {
+		cmp = a.ID - b.ID
+	}
+	if o.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// 件数指定による投稿の取得条件
+type PostPage struct {
+	PostScope
+	Order  PostOrder
+	Offset int // 読み飛ばす件数
+	Limit  int // 取得する最大件数
+}
+
+// 並び順の位置による投稿の取得条件
+// After・Before は並び順の項目の値とIDだけを使う(カーソルから復元した投稿を指定できる)
+type PostRange struct {
+	PostScope
+	Order   PostOrder // 並び順(空の場合はIDの昇順)
+	After   *Post     // この投稿より後に並ぶ投稿に限定する(nilの場合は制限なし)
+	Before  *Post     // この投稿より前に並ぶ投稿に限定する(nilの場合は制限なし)
+	Limit   int       // 取得する最大件数
+	Reverse bool      // trueの場合は並び順の逆順で取得する
+}
+
+// 投稿が範囲内に含まれるかを判定する(絞り込み条件は判定しない)
+func (r PostRange) inRange(post *Post) bool {
+	if r.After != nil && !r.Order.less(r.After, post) {
+		return false
+	}
+	if r.Before != nil && !r.Order.less(post, r.Before) {
+		return false
+	}
+	return true
+}
+
+// スレッドを上げられた順による投稿の取得条件
+// 並び順は LastBumpedAt の降順、同時刻の場合はIDの降順
+type BumpRange struct {
+	PostScope
+	AfterBumpedAt time.Time // この位置より後の投稿に限定する(AfterIDが0の場合は制限なし)
+	AfterID       int
+	Limit         int // 取得する最大件数
+}
+
+// 投稿が範囲内に含まれるかを判定する(絞り込み条件は判定しない)
+func (r BumpRange) inRange(post *Post) bool {
+	if r.AfterID == 0 {
+		return true
+	}
+	if post.LastBumpedAt.Equal(r.AfterBumpedAt) {
+		return post.ID < r.AfterID
+	}
+	return post.LastBumpedAt.Before(r.AfterBumpedAt)
+}
+
+// 全文検索の取得条件
+type SearchRange struct {
+	Query  search.Query
+	Offset int // 読み飛ばす件数
+	Limit  int // 取得する最大件数
+}
+
+// 検索結果からページの範囲を取り出す
+func (r SearchRange) page(hits []search.Hit) []search.Hit {
+	if r.Offset >= len(hits) {
+		return nil
+	}
+	hits = hits[r.Offset:]
+	if len(hits) > r.Limit {
+		hits = hits[:r.Limit]
+	}
+	return hits
+}
+
+// 全文検索の結果の1件
+type SearchHit struct {
+	Post  Post
+	Score float64 // 関連度(大きいほど関連が高い)
+}
+
+// 返信でスレッドを上げるかを判定する
+// replies は作成する返信を含まない、既存の返信数
+func bumps(comment *Comment, replies int, bumpLimit int) bool {
+	return !comment.Sage && (bumpLimit == 0 || replies < bumpLimit)
+}
+
+// スレッドに返信できるかを確認する
+// replies は作成する返信を含まない、既存の返信数
+func checkReply(post *Post, board *Board, replies int) error {
+	if post.Archived() {
+		return ThreadArchivedError()
+	}
+	if post.Locked || locks(board, replies) {
+		return ThreadLockedError(replies)
+	}
+	return nil
+}
+
+// 返信数が掲示板の返信数の上限に達しているかを判定する
+func locks(board *Board, replies int) bool {
+	return board.MaxReplies > 0 && replies >= board.MaxReplies
+}
+
+// 新しいスレッドを作成する前にアーカイブするスレッドの数を求める
+// live は作成するスレッドを含まない、アーカイブされていないスレッドの数
+func threadsToArchive(board *Board, live int) int {
+	if board.MaxThreads == 0 || live < board.MaxThreads {
+		return 0
+	}
+	return live - board.MaxThreads + 1
+}
+
+// コメントの取得条件
+type CommentRange struct {
+	PostID   int // 対象の投稿ID
+	ParentID int // 親コメントのID(0の場合は投稿への直接の返信)
+	AfterID  int // このIDより大きいコメントに限定する(0の場合は制限なし)
+	Limit    int // 取得する最大件数
+}
+
+// コメントが条件に一致するかを判定する
+func (r CommentRange) matches(c *Comment) bool {
+	return c.PostID == r.PostID && c.ParentID == r.ParentID && c.ID > r.AfterID
+}
+
+// 投稿が存在しない場合のエラー
+func postNotFound() *AppError {
+	return NotFoundError("post not found", "post not found")
+}
+
+// 版が存在しない場合のエラー
+func revisionNotFound() *AppError {
+	return NotFoundError("revision not found", "revision not found")
+}
+
+// 削除済みでない投稿を復元しようとした場合のエラー
+func deletedPostNotFound() *AppError {
+	return NotFoundError("deleted post not found", "post is not in the trash")
+}
+
+// 添付ファイルが見つからないエラーを作成する
+func attachmentNotFound() *AppError {
+	return NotFoundError("attachment not found", "attachment not found")
+}
+
+// 添付ファイルの内容が見つからないエラーを作成する
+func blobNotFound() *AppError {
+	return NotFoundError("file not found", "file not found")
+}
+
+// コメントが存在しない場合のエラー
+func commentNotFound() *AppError {
+	return NotFoundError("comment not found", "comment not found")
+}
+
+// 親コメントが別の投稿に属している場合のエラー
+func parentMismatch() *AppError {
+	return BadRequestError("parent comment belongs to another post", "parent comment belongs to another post")
+}
+
+// ユーザーが存在しない場合のエラー
+func userNotFound() *AppError {
+	return NotFoundError("user not found", "user not found")
+}
+
+// ユーザー名が既に使われている場合のエラー
+func usernameTaken() *AppError {
+	return ConflictError("username already taken", "username already taken")
+}
+
+// 掲示板が存在しない場合のエラー
+func boardNotFound() *AppError {
+	return NotFoundError("board not found", "board not found")
+}
+
+// スラッグが既に使われている場合のエラー
+func slugTaken() *AppError {
+	return ConflictError("board slug already taken", "board slug already taken")
+}
+
+// アーカイブ済みの掲示板にスレッドを作成しようとした場合のエラー
+func boardArchived() *AppError {
+	return ConflictError("board is archived", "board is archived and does not accept new threads")
+}
+
+// 掲示板に新しいスレッドを作成できるかを確認する
+func checkNewThread(board *Board) error {
+	if board.Archived {
+		return boardArchived()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ユーザーの権限
+type Role string
+
+const (
+	RoleMember    Role = "member"    // 一般ユーザー
+	RoleModerator Role = "moderator" // 投稿を管理するモデレーター
+	RoleAdmin     Role = "admin"     // 権限を管理する管理者
+)
+
+// 権限の強さ(大きいほど強い)
+var roleRanks = map[Role]int{
+	RoleMember:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// 有効な権限かを判定する
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// 指定した権限以上の権限を持つかを判定する
+func (r Role) AtLeast(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// ユーザーデータ構造体を定義する
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"` // bcryptでハッシュ化したパスワード
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
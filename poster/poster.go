@@ -0,0 +1,75 @@
+/*
+* 匿名投稿者の識別
+* 名前欄のトリップと、接続元から求める日替わりのIDを作成する
+ */
+
+package poster
+
+import (
+	"bbs-gql-project/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+// トリップの先頭に付ける記号
+const tripcodeMark = "◆"
+
+// 日替わりIDの日付の基準(日本時間の0時に切り替わる)
+var idLocation = time.FixedZone("JST", 9*60*60)
+
+// ソルト付きハッシュでトリップと日替わりIDを作成する
+type Hasher struct {
+	salt []byte
+}
+
+// ソルトを指定してHasherを作成する
+// 同じソルトを使い続ける限り、同じ入力から同じトリップ・IDが得られる
+func NewHasher(salt []byte) *Hasher {
+	return &Hasher{salt: salt}
+}
+
+// 名前欄の入力("名前#秘密の文字列")を表示名とトリップに分ける
+// 表示名が空の場合は既定の名前にする
+func (h *Hasher) Name(input string) (name string, tripcode string) {
+	name, secret, hasSecret := strings.Cut(input, "#")
+	// トリップを偽装できないよう、表示名の記号を置き換える
+	name = strings.TrimSpace(strings.ReplaceAll(name, tripcodeMark, "◇"))
+	if name == "" {
+		name = models.DefaultPosterName
+	}
+	if hasSecret && secret != "" {
+		tripcode = tripcodeMark + h.hash("trip", secret)[:10]
+	}
+	return name, tripcode
+}
+
+// 接続元のIPアドレスと日付から日替わりのIDを作成する
+func (h *Hasher) PosterID(ip string, now time.Time) string {
+	return h.hash("id", ip+"/"+now.In(idLocation).Format("2006-01-02"))[:8]
+}
+
+// 用途ごとに区別したソルト付きハッシュを計算する
+func (h *Hasher) hash(purpose string, value string) string {
+	mac := hmac.New(sha256.New, h.salt)
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// コンテキストのキー
+type contextKey struct{}
+
+// 接続元のIPアドレスをコンテキストに設定する
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// コンテキストから接続元のIPアドレスを取得する
+// 設定されていない場合は空文字列を返す
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(contextKey{}).(string)
+	return ip
+}
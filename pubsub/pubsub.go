@@ -0,0 +1,61 @@
+/*
+* プロセス内のPub/Sub
+* ミューテーションで発生したイベントをサブスクリプションに配信する
+ */
+
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// 購読者ごとのバッファサイズ
+// 受信が追いつかない購読者へのイベントは破棄する(配信元をブロックしない)
+const subscriberBuffer = 16
+
+// T型のイベントを配信するブローカー
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[int]chan T
+	nextID int
+}
+
+// ブローカーを作成する
+func New[T any]() *Broker[T] {
+	return &Broker[T]{subs: map[int]chan T{}}
+}
+
+// イベントを購読する
+// ctxが終了すると購読を解除し、チャネルを閉じる
+func (b *Broker[T]) Subscribe(ctx context.Context) <-chan T {
+	ch := make(chan T, subscriberBuffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, id)
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch
+}
+
+// すべての購読者にイベントを配信する
+func (b *Broker[T]) Publish(event T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
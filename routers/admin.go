@@ -0,0 +1,49 @@
+/*
+* 管理者ユーザーの作成
+ */
+
+package routers
+
+import (
+	"bbs-gql-project/auth"
+	"bbs-gql-project/config"
+	"bbs-gql-project/models"
+	"bbs-gql-project/validation"
+	"context"
+	"fmt"
+	"time"
+)
+
+// 設定で指定された管理者ユーザーを作成する
+// 登録による乗っ取りを防ぐため、同じ名前の管理者でないユーザーが既にいる場合は権限を付与せずにエラーにする
+// 既に管理者として存在する場合は何もしない(パスワードも変更しない)
+func provisionAdmin(ctx context.Context, cfg *config.Config, users models.UserStore, now time.Time) error {
+	if cfg.AdminUsername == "" {
+		return nil
+	}
+	if err := validation.Credentials(cfg.AdminUsername, cfg.AdminPassword); err != nil {
+		return fmt.Errorf("invalid BBS_ADMIN_USERNAME or BBS_ADMIN_PASSWORD: %w", err)
+	}
+
+	existing, err := users.GetByUsername(ctx, cfg.AdminUsername)
+	if err == nil {
+		if existing.Role != models.RoleAdmin {
+			return fmt.Errorf("BBS_ADMIN_USERNAME %q is already registered as a non-admin user", cfg.AdminUsername)
+		}
+		return nil
+	}
+	if !models.IsKind(err, models.ErrorKindNotFound) {
+		return err
+	}
+
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	return users.Create(ctx, &models.User{
+		Username:     cfg.AdminUsername,
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		CreatedAt:    now,
+	})
+}
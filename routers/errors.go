@@ -0,0 +1,55 @@
+/*
+* GraphQLのエラー出力を設定する
+ */
+
+package routers
+
+import (
+	"bbs-gql-project/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// AppErrorをGraphQLのエラーに変換するプレゼンターを作成する
+// エラー情報は errors[].extensions に code, httpStatus, detail, fields として出力する
+// 本番環境では 500 エラーの詳細を出力しない
+func errorPresenter(production bool) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+		var appErr *models.AppError
+		if !errors.As(err, &appErr) {
+			return gqlErr
+		}
+
+		gqlErr.Message = appErr.Message
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]interface{}{}
+		}
+		gqlErr.Extensions["code"] = appErr.Kind
+		gqlErr.Extensions["httpStatus"] = appErr.Code
+		if !production || appErr.Code < http.StatusInternalServerError {
+			gqlErr.Extensions["detail"] = appErr.Detail
+		}
+		if len(appErr.Fields) > 0 {
+			gqlErr.Extensions["fields"] = appErr.Fields
+		}
+		for key, value := range appErr.Extensions {
+			gqlErr.Extensions[key] = value
+		}
+		return gqlErr
+	}
+}
+
+// リゾルバ内で発生したパニックを 500 Internal Server Error に変換する
+func recoverFunc(ctx context.Context, err interface{}) error {
+	log.Printf("panic in resolver: %v\n%s", err, debug.Stack())
+	return models.InternalServerError("internal server error", fmt.Sprint(err))
+}
@@ -0,0 +1,81 @@
+/*
+* 添付ファイルの配信
+ */
+
+package routers
+
+import (
+	"bbs-gql-project/models"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// 添付ファイルをキャッシュしてよい期間
+// 内容は変更されないが、投稿の削除後に配信を止められるよう期限を設け、以降は ETag で再検証させる
+const fileMaxAge = 24 * time.Hour
+
+// 添付ファイルを配信するハンドラ
+// 投稿時に内容から判定したMIMEタイプで配信し、ブラウザに内容からの推測やスクリプトの実行をさせない
+// 条件付きリクエスト(If-None-Match、If-Modified-Since)と範囲リクエストにも応答する
+func fileHandler(attachments models.AttachmentStore, blobs models.BlobStore, production bool) gin.HandlerFunc {
+	present := errorPresenter(production)
+	abort := func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		var appErr *models.AppError
+		if errors.As(err, &appErr) {
+			status = appErr.Code
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"errors": gqlerror.List{present(c.Request.Context(), err)},
+		})
+	}
+
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			abort(c, models.NotFoundError("attachment not found", "invalid attachment ID"))
+			return
+		}
+		attachment, err := attachments.Get(c.Request.Context(), id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		file, err := blobs.Open(c.Request.Context(), attachment.BlobKey)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		defer file.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", attachment.ContentType)
+		header.Set("Content-Disposition", contentDisposition(attachment))
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(fileMaxAge.Seconds())))
+		header.Set("ETag", `"`+attachment.BlobKey+`"`)
+		http.ServeContent(c.Writer, c.Request, "", attachment.CreatedAt, file)
+	}
+}
+
+// Content-Disposition ヘッダーの値を作成する
+// 画像はページ内に表示し、それ以外はダウンロードさせる(ファイル名はRFC 2231の形式で符号化する)
+func contentDisposition(attachment *models.Attachment) string {
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}); value != "" {
+		return value
+	}
+	return disposition
+}
@@ -0,0 +1,92 @@
+/*
+* Ginのミドルウェア
+ */
+
+package routers
+
+import (
+	"bbs-gql-project/auth"
+	"bbs-gql-project/models"
+	"bbs-gql-project/poster"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// 接続元のIPアドレスをコンテキストに設定する(日替わりIDの計算に使用する)
+func clientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(poster.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// Authorization ヘッダーのBearerトークンを検証し、認証済みユーザーをコンテキストに設定する
+// トークンがない場合は未認証のまま処理を続け、不正なトークンの場合は 401 を返す
+func authMiddleware(tokens *auth.Tokens, users models.UserStore, now func() time.Time, production bool) gin.HandlerFunc {
+	present := errorPresenter(production)
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		user, err := authenticate(c.Request.Context(), header, tokens, users, now())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"errors": gqlerror.List{present(c.Request.Context(), err)},
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithUser(c.Request.Context(), user))
+		c.Next()
+	}
+}
+
+// WebSocketの connection_init の payload に含まれるBearerトークンを検証し、認証済みユーザーをコンテキストに設定する
+// ブラウザはアップグレード要求にヘッダーを付けられないため、payload の Authorization で認証する
+// payload にトークンがない場合はアップグレード要求での認証結果をそのまま使い、不正なトークンの場合は接続を拒否する
+func websocketInit(tokens *auth.Tokens, users models.UserStore, now func() time.Time) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		header := payload.Authorization()
+		if header == "" {
+			return ctx, nil, nil
+		}
+
+		user, err := authenticate(ctx, header, tokens, users, now())
+		if err != nil {
+			return ctx, nil, err
+		}
+		return auth.WithUser(ctx, user), nil, nil
+	}
+}
+
+// Authorization ヘッダーの値からユーザーを特定する
+func authenticate(ctx context.Context, header string, tokens *auth.Tokens, users models.UserStore, now time.Time) (*models.User, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return nil, auth.TokenMalformedError("authorization header must be in the form \"Bearer <token>\"")
+	}
+
+	userID, err := tokens.Verify(token, now)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := users.Get(ctx, userID)
+	if models.IsKind(err, models.ErrorKindNotFound) {
+		return nil, auth.TokenInvalidError("user no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
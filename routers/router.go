@@ -5,17 +5,121 @@
 package routers
 
 import (
+	"bbs-gql-project/auth"
+	"bbs-gql-project/config"
 	"bbs-gql-project/graph"
+	"bbs-gql-project/markup"
+	"bbs-gql-project/models"
+	"bbs-gql-project/poster"
+	"bbs-gql-project/pubsub"
+	"bbs-gql-project/validation"
+	"context"
+	"crypto/rand"
+	"log"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ルーターの設定項目
+type options struct {
+	config *config.Config
+	store  models.Store
+	blobs  models.BlobStore
+	clock  func() time.Time
+}
+
+// ルーターの設定を変更する関数
+type Option func(*options)
+
+// アプリケーションの設定を指定する
+// 指定しない場合はデフォルトの設定を使用する
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// データの保存先を指定する
+// 指定しない場合はサンプルデータを持つインメモリのストアを使用する
+func WithStore(store models.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// 添付ファイルの内容の保存先を指定する
+// 指定しない場合はメモリ上の保存先を使用する
+func WithBlobStore(blobs models.BlobStore) Option {
+	return func(o *options) {
+		o.blobs = blobs
+	}
+}
+
+// 現在時刻の取得方法を指定する
+// 指定しない場合はシステムの時刻を使用する
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// 設定に従ってアクセストークンの発行・検証を行うオブジェクトを作成する
+func newTokens(cfg *config.Config) *auth.Tokens {
+	if cfg.JWTAlgorithm == config.JWTAlgorithmEdDSA {
+		return auth.NewEdDSATokens(cfg.JWTEd25519Key, cfg.TokenTTL)
+	}
+	// 設定されていない場合は起動ごとにランダムな鍵を生成する(再起動でトークンは無効になる)
+	secret := secretOrRandom(cfg.JWTSecret, "BBS_JWT_SECRET", "tokens will be invalidated on restart")
+	return auth.NewTokens(secret, cfg.TokenTTL)
+}
+
+// 設定された秘密の値を返す
+// 設定されていない場合はランダムな値を生成し、その影響をログに出力する
+func secretOrRandom(value string, env string, consequence string) []byte {
+	if value != "" {
+		return []byte(value)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	log.Printf("%s is not set; using a random key (%s)", env, consequence)
+	return secret
+}
+
 // GraphQLハンドラを定義
-func graphqlHandler() gin.HandlerFunc {
-	h := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{}}))
+// handler.NewDefaultServer と同じ構成で、multipart リクエストの大きさだけを添付ファイルの上限に合わせる
+func graphqlHandler(cfg *config.Config, resolver *graph.Resolver, now func() time.Time) gin.HandlerFunc {
+	h := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.NewDirectives(resolver),
+	}))
+	h.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              websocketInit(resolver.Tokens, resolver.UserStore, now),
+	})
+	h.AddTransport(transport.Options{})
+	h.AddTransport(transport.GET{})
+	h.AddTransport(transport.POST{})
+	// 添付ファイルの合計に加え、クエリと変数の分として1MiBを許容する
+	h.AddTransport(transport.MultipartForm{
+		MaxUploadSize: int64(cfg.MaxAttachments)*int64(cfg.MaxAttachmentSize) + 1<<20,
+	})
+	h.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	h.Use(extension.Introspection{})
+	h.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New[string](100),
+	})
+	h.SetErrorPresenter(errorPresenter(cfg.Production))
+	h.SetRecoverFunc(recoverFunc)
 
 	return func(c *gin.Context) {
 		h.ServeHTTP(c.Writer, c.Request)
@@ -32,15 +136,74 @@ func playgroundHandler() gin.HandlerFunc {
 }
 
 // ルーティングの設定
-func SetupRouter() *gin.Engine {
+func SetupRouter(opts ...Option) *gin.Engine {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.config == nil {
+		o.config = config.Default()
+	}
+	if o.store == nil {
+		o.store = models.NewMemoryStore(models.SeedPosts)
+	}
+	if o.blobs == nil {
+		o.blobs = models.NewMemoryBlobStore()
+	}
+	if o.clock == nil {
+		o.clock = time.Now
+	}
+	tokens := newTokens(o.config)
+	if err := provisionAdmin(context.Background(), o.config, o.store.Users(), o.clock()); err != nil {
+		panic(err)
+	}
+
+	resolver := &graph.Resolver{
+		PostStore:       o.store.Posts(),
+		CommentStore:    o.store.Comments(),
+		UserStore:       o.store.Users(),
+		BoardStore:      o.store.Boards(),
+		RevisionStore:   o.store.Revisions(),
+		QuoteStore:      o.store.Quotes(),
+		AttachmentStore: o.store.Attachments(),
+		BlobStore:       o.blobs,
+		Clock:           o.clock,
+		BumpLimit:       o.config.BumpLimit,
+		Limits: validation.Limits{
+			MaxTitleLength:    o.config.MaxTitleLength,
+			MaxContentLength:  o.config.MaxContentLength,
+			MaxAttachments:    o.config.MaxAttachments,
+			MaxAttachmentSize: o.config.MaxAttachmentSize,
+		},
+		Tokens:         tokens,
+		AllowAnonymous: o.config.AllowAnonymous,
+		Poster:         poster.NewHasher(secretOrRandom(o.config.PosterSalt, "BBS_POSTER_SALT", "tripcodes and poster IDs will change on restart")),
+		Markup:         markup.NewRenderer(),
+		PostEvents:     pubsub.New[models.PostEvent](),
+		CommentEvents:  pubsub.New[models.Comment](),
+	}
+
 	r := gin.Default()
+	// 信頼するプロキシ以外からの X-Forwarded-For は無視する(日替わりIDの偽装を防ぐ)
+	if err := r.SetTrustedProxies(o.config.TrustedProxies); err != nil {
+		panic(err)
+	}
 
 	// /v1/gql に関連するエンドポイントをグループ化
 	api := r.Group("/v1/gql")
 	{
-		api.POST("/query", graphqlHandler())
+		gql := graphqlHandler(o.config, resolver, o.clock)
+		authenticated := authMiddleware(tokens, resolver.UserStore, o.clock, o.config.Production)
+		api.POST("/query", clientIPMiddleware(), authenticated, gql)
+		// サブスクリプション(graphql-ws)はWebSocketへのアップグレード要求として受け付ける
+		api.GET("/query", clientIPMiddleware(), authenticated, gql)
 		api.GET("/", playgroundHandler())
 	}
 
+	// 添付ファイルの配信
+	files := fileHandler(resolver.AttachmentStore, resolver.BlobStore, o.config.Production)
+	r.GET(graph.AttachmentPath+":id", files)
+	r.HEAD(graph.AttachmentPath+":id", files)
+
 	return r
 }
@@ -0,0 +1,239 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25のパラメータ
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// タイトルに一致した場合のスコアの重み(本文は1)
+const titleWeight = 2.0
+
+// 検索結果の1件
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// 文書の1項目(タイトルまたは本文)
+type field struct {
+	text   string         // 正規化した文字列(フレーズの照合に使う)
+	terms  map[string]int // トークンごとの出現回数
+	length int            // トークン数(文書の長さによるスコアの補正に使う)
+}
+
+// 文字列から項目を作成する
+func newField(s string) field {
+	f := field{text: Normalize(s), terms: map[string]int{}}
+	for _, token := range tokenize(f.text, true) {
+		f.terms[token]++
+	}
+	// 日本語の1文字のトークンは長さに含めない(2-gramと二重に数えないため)
+	f.length = len(tokenize(f.text, false))
+	return f
+}
+
+// 語句が項目に含まれるかを判定する
+// トークンがすべて含まれ、かつ正規化した文字列に語句がそのまま含まれる場合に一致する
+func (f *field) matches(term string) bool {
+	for _, token := range tokenize(term, false) {
+		if f.terms[token] == 0 {
+			return false
+		}
+	}
+	return strings.Contains(f.text, term)
+}
+
+// インデックスに登録した文書
+type document struct {
+	title   field
+	content field
+}
+
+// 語句が文書のタイトルまたは本文に含まれるかを判定する
+func (d *document) matches(term string) bool {
+	return d.title.matches(term) || d.content.matches(term)
+}
+
+// 投稿のタイトルと本文の転置インデックス
+// 複数のリクエストから並行して読み書きされるため、ロックで保護する
+type Index struct {
+	mu            sync.RWMutex
+	docs          map[int]*document
+	postings      map[string]map[int]struct{} // トークンを含む文書のID
+	titleLength   int                         // 全文書のタイトルのトークン数の合計
+	contentLength int                         // 全文書の本文のトークン数の合計
+}
+
+// 空のインデックスを作成する
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[int]*document{},
+		postings: map[string]map[int]struct{}{},
+	}
+}
+
+// 文書を登録する(同じIDの文書が登録済みの場合は置き換える)
+func (ix *Index) Add(id int, title string, content string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+	doc := &document{title: newField(title), content: newField(content)}
+	ix.docs[id] = doc
+	ix.titleLength += doc.title.length
+	ix.contentLength += doc.content.length
+	for _, f := range []field{doc.title, doc.content} {
+		for token := range f.terms {
+			if ix.postings[token] == nil {
+				ix.postings[token] = map[int]struct{}{}
+			}
+			ix.postings[token][id] = struct{}{}
+		}
+	}
+}
+
+// 文書を削除する
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+// 文書を削除する(ロックは呼び出し元で取得する)
+func (ix *Index) remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, f := range []field{doc.title, doc.content} {
+		for token := range f.terms {
+			delete(ix.postings[token], id)
+			if len(ix.postings[token]) == 0 {
+				delete(ix.postings, token)
+			}
+		}
+	}
+	ix.titleLength -= doc.title.length
+	ix.contentLength -= doc.content.length
+	delete(ix.docs, id)
+}
+
+// 検索条件に一致する文書を、スコアの降順(同じスコアの場合はIDの降順)で返す
+func (ix *Index) Search(q Query) []Hit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	tokens := queryTokens(q)
+
+	hits := []Hit{}
+	for id := range ix.candidates(tokens) {
+		doc := ix.docs[id]
+		if !ix.accepts(doc, q) {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: ix.score(doc, tokens)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits
+}
+
+// 含む語句のトークンを重複を除いて返す
+// スコアの計算結果が毎回同じになるよう、トークンは並び替える
+func queryTokens(q Query) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, term := range q.Include {
+		for _, token := range tokenize(term, false) {
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// すべてのトークンを含む文書のIDを返す(ロックは呼び出し元で取得する)
+func (ix *Index) candidates(tokens []string) map[int]struct{} {
+	// 含む文書が最も少ないトークンから絞り込む
+	var smallest map[int]struct{}
+	for _, token := range tokens {
+		posting := ix.postings[token]
+		if smallest == nil || len(posting) < len(smallest) {
+			smallest = posting
+		}
+	}
+
+	result := map[int]struct{}{}
+	for id := range smallest {
+		all := true
+		for _, token := range tokens {
+			if _, ok := ix.postings[token][id]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
+
+// 文書が語句の条件(含む語句・含まない語句)を満たすかを判定する
+func (ix *Index) accepts(doc *document, q Query) bool {
+	for _, term := range q.Include {
+		if !doc.matches(term) {
+			return false
+		}
+	}
+	for _, term := range q.Exclude {
+		if doc.matches(term) {
+			return false
+		}
+	}
+	return true
+}
+
+// BM25でタイトルと本文のスコアを計算する(ロックは呼び出し元で取得する)
+func (ix *Index) score(doc *document, tokens []string) float64 {
+	n := float64(len(ix.docs))
+	avgTitle := float64(ix.titleLength) / n
+	avgContent := float64(ix.contentLength) / n
+
+	score := 0.0
+	for _, token := range tokens {
+		df := float64(len(ix.postings[token]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * (titleWeight*bm25(doc.title, token, avgTitle) + bm25(doc.content, token, avgContent))
+	}
+	return score
+}
+
+// 1項目のBM25の単語頻度の項を計算する
+func bm25(f field, token string, avgLength float64) float64 {
+	tf := float64(f.terms[token])
+	if tf == 0 {
+		return 0
+	}
+	norm := 1 - bm25B
+	if avgLength > 0 {
+		norm += bm25B * float64(f.length) / avgLength
+	}
+	return tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"errors"
+	"strings"
+)
+
+// 検索語が含まれていない場合のエラー
+var ErrNoTerms = errors.New("query must contain at least one search term")
+
+// 検索条件
+// 語句はすべて正規化済みで、Includeの語句をすべて含み、Excludeの語句を含まない投稿に一致する
+type Query struct {
+	Include []string // 含む語句("..." で囲んだフレーズは空白を含む)
+	Exclude []string // 含まない語句(先頭に - を付けた語句)
+}
+
+// 検索文字列を解析する
+// 空白区切りの語句はすべて含むもの(AND)として扱い、"..." はフレーズ、-語句 は除外を表す
+// 閉じられていない " は文字列の末尾までをフレーズとする
+func ParseQuery(input string) (Query, error) {
+	var q Query
+	s := Normalize(input)
+	for len(s) > 0 {
+		exclude := false
+		if s[0] == '-' && len(s) > 1 && s[1] != ' ' {
+			exclude = true
+			s = s[1:]
+		}
+
+		var term string
+		if s[0] == '"' {
+			var ok bool
+			if term, s, ok = strings.Cut(s[1:], `"`); !ok {
+				s = ""
+			}
+		} else {
+			term, s, _ = strings.Cut(s, " ")
+		}
+		s = strings.TrimLeft(s, " ")
+
+		// 区切り文字だけの語句は無視する
+		term = strings.TrimSpace(term)
+		if len(tokenize(term, false)) == 0 {
+			continue
+		}
+		if exclude {
+			q.Exclude = append(q.Exclude, term)
+		} else {
+			q.Include = append(q.Include, term)
+		}
+	}
+	if len(q.Include) == 0 {
+		return Query{}, ErrNoTerms
+	}
+	return q, nil
+}
@@ -0,0 +1,80 @@
+/*
+* 全文検索
+* 日本語を文字の2-gram、それ以外を単語に分割して転置インデックスを作成する
+ */
+
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 文字種
+type charClass int
+
+const (
+	separator charClass = iota // 区切り文字(空白・記号)
+	word                       // 英数字など、空白で区切られる文字
+	cjk                        // 漢字・ひらがな・カタカナ(2-gramに分割する)
+)
+
+// 文字種を判定する
+func classify(r rune) charClass {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana), r == 'ー':
+		return cjk
+	case unicode.IsLetter(r), unicode.IsDigit(r):
+		return word
+	}
+	return separator
+}
+
+// 検索用に文字列を正規化する
+// 全角英数字・半角カナをNFKCで統一し、小文字にして空白の連続を1文字にまとめる
+func Normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFKC.String(s))), " ")
+}
+
+// 正規化済みの文字列をトークンに分割する
+// 英数字は連続する文字を1トークン、日本語は連続する文字の2-gramを1トークンとする
+// unigramsがtrueの場合は日本語の1文字も1トークンとして含める(1文字の検索語に一致させるため)
+func tokenize(s string, unigrams bool) []string {
+	tokens := []string{}
+	runes := []rune(s)
+	for start := 0; start < len(runes); {
+		class := classify(runes[start])
+		end := start + 1
+		for end < len(runes) && classify(runes[end]) == class {
+			end++
+		}
+		run := runes[start:end]
+		switch class {
+		case word:
+			tokens = append(tokens, string(run))
+		case cjk:
+			tokens = append(tokens, cjkTokens(run, unigrams)...)
+		}
+		start = end
+	}
+	return tokens
+}
+
+// 日本語の連続する文字を2-gramに分割する(1文字の場合はその文字)
+func cjkTokens(run []rune, unigrams bool) []string {
+	if len(run) == 1 {
+		return []string{string(run)}
+	}
+	tokens := make([]string, 0, len(run)*2)
+	for i := range run {
+		if unigrams {
+			tokens = append(tokens, string(run[i]))
+		}
+		if i+1 < len(run) {
+			tokens = append(tokens, string(run[i:i+2]))
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,138 @@
+package resolver_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 投稿の参照元を "投稿ID" または "投稿ID/コメントID" の形式で取得する
+func backlinks(t *testing.T, r *gin.Engine, postID string) []string {
+	t.Helper()
+
+	response := doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { quotedBy { post { id } comment { id } } } }`, postID))
+	require.Nil(t, response["errors"])
+	result := []string{}
+	for _, item := range response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["quotedBy"].([]interface{}) {
+		backlink := item.(map[string]interface{})
+		source := backlink["post"].(map[string]interface{})["id"].(string)
+		if comment, ok := backlink["comment"].(map[string]interface{}); ok {
+			source += "/" + comment["id"].(string)
+		}
+		result = append(result, source)
+	}
+	return result
+}
+
+// 投稿の本文が参照している投稿のIDを取得する
+func quotes(t *testing.T, r *gin.Engine, postID string) []string {
+	t.Helper()
+
+	response := doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { quotes { id } } }`, postID))
+	require.Nil(t, response["errors"])
+	result := []string{}
+	for _, item := range response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["quotes"].([]interface{}) {
+		result = append(result, item.(map[string]interface{})["id"].(string))
+	}
+	return result
+}
+
+// 参照先の投稿と、それを単独・範囲のアンカーで参照する投稿を作成し、両方の投稿IDを返す
+func createAnchorPosts(t *testing.T, r *gin.Engine) (string, string) {
+	t.Helper()
+
+	response := doQuery(t, r, `mutation { createPost(input: {title: "参照先", content: "本文"}) { id } }`)
+	target := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+	response = doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: "参照元", content: ">>%s を参照 >>1-2"}) { id } }`, target))
+	require.Nil(t, response["errors"])
+	return target, response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+}
+
+// 単独のアンカーと範囲のアンカーを解析し、HTMLではリンクにする
+func TestAnchors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		content := ">>3 を参照 >>1-2\n>>0 と >>x は無視"
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: "参照元", content: %q}) { id contentHtml } }`, content))
+		require.Nil(t, response["errors"])
+		post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+		assert.Equal(t, `<p><a href="#post-3" class="anchor">&gt;&gt;3</a> を参照 <a href="#post-1" class="anchor">&gt;&gt;1-2</a><br>`+"\n"+`&gt;&gt;0 と &gt;&gt;x は無視</p>`+"\n", post["contentHtml"])
+		assert.Equal(t, []string{"1", "2", "3"}, quotes(t, r, post["id"].(string)))
+		assert.Equal(t, []string{post["id"].(string)}, backlinks(t, r, "3"))
+	})
+}
+
+// Markdownでは行頭のアンカーを引用にせずリンクにする
+func TestMarkdownAnchors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		html := markdownHTML(t, r, ">>3\n\n> 引用")
+		assert.Contains(t, html, `<p><a href="#post-3" class="anchor" rel="nofollow ugc">&gt;&gt;3</a></p>`)
+		assert.Contains(t, html, "<blockquote>\n<p>引用</p>\n</blockquote>")
+	})
+}
+
+// コメント(返信を含む)からの参照も被参照に含め、コメントを削除すると返信からの参照も消える
+func TestCommentAnchors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		target, source := createAnchorPosts(t, r)
+
+		parent := createComment(t, r, "1", "", "親コメント")
+		reply := createComment(t, r, "1", parent, ">>"+target)
+		assert.Equal(t, []string{"1/" + reply, source}, backlinks(t, r, target))
+
+		doQueryAs(t, r, adminToken(t, r), fmt.Sprintf(`mutation { deleteComment(id: %q) }`, parent))
+		assert.Equal(t, []string{source}, backlinks(t, r, target))
+	})
+}
+
+// 本文を更新すると参照し直す
+func TestAnchorsAfterUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		target, source := createAnchorPosts(t, r)
+
+		response := doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updatePost(id: %q, input: {content: ">>2"}) { id } }`, source))
+		require.Nil(t, response["errors"])
+		assert.Equal(t, []string{"2"}, quotes(t, r, source))
+		assert.Empty(t, backlinks(t, r, target))
+		assert.Equal(t, []string{source}, backlinks(t, r, "2"))
+	})
+}
+
+// 削除済みの投稿からの参照は表示せず、元に戻すと再び表示する
+func TestAnchorsFromDeletedPost(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		target, source := createAnchorPosts(t, r)
+
+		doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: %q) }`, source))
+		assert.Empty(t, backlinks(t, r, target))
+		response := doQueryAs(t, r, admin, fmt.Sprintf(`mutation { restorePost(id: %q) { id } }`, source))
+		require.Nil(t, response["errors"])
+		assert.Equal(t, []string{source}, backlinks(t, r, target))
+	})
+}
+
+// 再起動後も保存済みの本文からアンカーの参照を復元する
+func TestSQLiteAnchorsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bbs.db")
+	r, _ := setupSQLiteRouter(t, path)
+	response := doQuery(t, r, `mutation { createPost(input: {title: "参照元", content: ">>3"}) { id } }`)
+	source := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+	comment := createComment(t, r, "4", "", ">>3")
+
+	r, _ = setupSQLiteRouter(t, path)
+	assert.Equal(t, []string{"4/" + comment, source}, backlinks(t, r, "3"))
+}
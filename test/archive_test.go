@@ -0,0 +1,140 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 掲示板のスレッド一覧(field は threads または archivedThreads)のタイトルと総数を取得する
+func boardThreadTitles(t *testing.T, r *gin.Engine, field string) ([]string, float64) {
+	t.Helper()
+
+	response := doQuery(t, r, `query { board(slug: "arc") { `+field+` { totalCount edges { node { title } } } } }`)
+	require.Nil(t, response["errors"])
+	threads := response["data"].(map[string]interface{})["board"].(map[string]interface{})[field].(map[string]interface{})
+	titles := []string{}
+	for _, edge := range threads["edges"].([]interface{}) {
+		titles = append(titles, edge.(map[string]interface{})["node"].(map[string]interface{})["title"].(string))
+	}
+	return titles, threads["totalCount"].(float64)
+}
+
+// 管理者として掲示板 arc(スレッドは2件、返信は2件まで)を作成し、管理者のトークンを返す
+func createArchiveBoard(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+
+	admin := adminToken(t, r)
+	response := doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "arc", name: "過去ログ", maxThreads: 2, maxReplies: 2}) { maxReplies } }`)
+	assert.Equal(t, float64(2), response["data"].(map[string]interface{})["createBoard"].(map[string]interface{})["maxReplies"])
+	return admin
+}
+
+// 時計を進めて掲示板 arc にスレッドを作成し、投稿IDを返す
+func createArchiveThread(t *testing.T, r *gin.Engine, advance func(), title string) string {
+	t.Helper()
+
+	advance()
+	response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: %q, content: "本文", boardSlug: "arc"}) { id } }`, title))
+	require.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+}
+
+// 時計を進めてスレッドに返信する
+func replyToThread(t *testing.T, r *gin.Engine, advance func(), id string) map[string]interface{} {
+	t.Helper()
+
+	advance()
+	return doQuery(t, r, fmt.Sprintf(`mutation { createComment(input: {postId: %q, content: "返信"}) { id } }`, id))
+}
+
+// スレッドのロック・アーカイブの状態を取得する
+func threadState(t *testing.T, r *gin.Engine, id string) map[string]interface{} {
+	t.Helper()
+
+	response := doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { locked archived archivedAt } }`, id))
+	require.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+}
+
+// スレッド数の上限を超えると、最後に上げられたのが最も古いスレッドがアーカイブされる
+func TestThreadArchiving(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		createArchiveBoard(t, r)
+
+		a := createArchiveThread(t, r, advance, "A")
+		b := createArchiveThread(t, r, advance, "B")
+		assert.Nil(t, replyToThread(t, r, advance, a)["errors"])
+		createArchiveThread(t, r, advance, "C")
+		titles, total := boardThreadTitles(t, r, "threads")
+		assert.Equal(t, []string{"A", "C"}, titles)
+		assert.Equal(t, float64(2), total)
+		titles, total = boardThreadTitles(t, r, "archivedThreads")
+		assert.Equal(t, []string{"B"}, titles)
+		assert.Equal(t, float64(1), total)
+		assert.Equal(t, true, threadState(t, r, b)["archived"])
+		assert.NotNil(t, threadState(t, r, b)["archivedAt"])
+		assert.Nil(t, threadState(t, r, a)["archivedAt"])
+
+		// アーカイブ済みのスレッドには返信できない
+		message, ext := firstErrorExtensions(t, replyToThread(t, r, advance, b))
+		assert.Equal(t, "thread is archived", message)
+		assert.Equal(t, "THREAD_ARCHIVED", ext["code"])
+	})
+}
+
+// 返信数の上限に達するとスレッドがロックされる
+func TestThreadLocking(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		admin := createArchiveBoard(t, r)
+		a := createArchiveThread(t, r, advance, "A")
+
+		assert.Nil(t, replyToThread(t, r, advance, a)["errors"])
+		assert.Equal(t, false, threadState(t, r, a)["locked"])
+		assert.Nil(t, replyToThread(t, r, advance, a)["errors"])
+		assert.Equal(t, true, threadState(t, r, a)["locked"])
+		_, ext := firstErrorExtensions(t, replyToThread(t, r, advance, a))
+		assert.Equal(t, "THREAD_LOCKED", ext["code"])
+		assert.Equal(t, "the thread is locked with 2 replies and is read-only", ext["detail"])
+
+		// 上限を下げても、エラーはスレッドの実際の返信数を示す
+		response := doQueryAs(t, r, admin, `mutation { updateBoardLimits(slug: "arc", input: {maxReplies: 1}) { maxReplies } }`)
+		assert.Nil(t, response["errors"])
+		_, ext = firstErrorExtensions(t, replyToThread(t, r, advance, a))
+		assert.Equal(t, "the thread is locked with 2 replies and is read-only", ext["detail"])
+	})
+}
+
+// 上限設定の変更は管理者のみで、下げた場合は次のスレッドの作成時に超えた分をまとめてアーカイブする
+func TestUpdateBoardLimits(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		admin := createArchiveBoard(t, r)
+		createArchiveThread(t, r, advance, "A")
+		createArchiveThread(t, r, advance, "B")
+
+		member := registerToken(t, r, "taro")
+		limits := `mutation { updateBoardLimits(slug: "arc", input: {maxThreads: 1}) { maxThreads maxReplies } }`
+		_, ext := firstErrorExtensions(t, doQueryAs(t, r, member, limits))
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		response := doQueryAs(t, r, admin, `mutation { updateBoardLimits(slug: "arc", input: {maxReplies: -1}) { maxReplies } }`)
+		assert.Equal(t, []string{"maxReplies"}, errorFields(t, response))
+		response = doQueryAs(t, r, admin, limits)
+		updated := response["data"].(map[string]interface{})["updateBoardLimits"].(map[string]interface{})
+		assert.Equal(t, float64(1), updated["maxThreads"])
+		assert.Equal(t, float64(2), updated["maxReplies"])
+
+		createArchiveThread(t, r, advance, "D")
+		titles, _ := boardThreadTitles(t, r, "threads")
+		assert.Equal(t, []string{"D"}, titles)
+		titles, _ = boardThreadTitles(t, r, "archivedThreads")
+		assert.Equal(t, []string{"A", "B"}, titles)
+	})
+}
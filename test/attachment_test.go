@@ -0,0 +1,282 @@
+package resolver_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// テスト用のファイルの内容(先頭のシグネチャで種類が判定される)
+var (
+	pngData  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfData  = []byte("%PDF-1.4\n%テスト\n")
+	htmlData = []byte("<html><script>alert(1)</script></html>")
+)
+
+// アップロードするファイル
+type uploadFile struct {
+	name string
+	data []byte
+}
+
+// createPost に添付ファイルを付けて GraphQL multipart request で送信する
+func createPostWithFiles(t *testing.T, r *gin.Engine, files ...uploadFile) map[string]interface{} {
+	t.Helper()
+	return createPostWithTitle(t, r, "添付", files...)
+}
+
+// タイトルを指定し、createPost に添付ファイルを付けて GraphQL multipart request で送信する
+func createPostWithTitle(t *testing.T, r *gin.Engine, title string, files ...uploadFile) map[string]interface{} {
+	t.Helper()
+
+	placeholders := make([]interface{}, len(files))
+	fileMap := map[string][]string{}
+	for i := range files {
+		fileMap[fmt.Sprint(i)] = []string{fmt.Sprintf("variables.attachments.%d", i)}
+	}
+	operations, _ := json.Marshal(map[string]interface{}{
+		"query":     `mutation ($title: String!, $attachments: [Upload!]) { createPost(input: {title: $title, content: "本文"}, attachments: $attachments) { id attachments { id filename contentType size url } } }`,
+		"variables": map[string]interface{}{"title": title, "attachments": placeholders},
+	})
+	mapping, _ := json.Marshal(fileMap)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("operations", string(operations)))
+	require.NoError(t, writer.WriteField("map", string(mapping)))
+	for i, file := range files {
+		part, err := writer.CreateFormFile(fmt.Sprint(i), file.name)
+		require.NoError(t, err)
+		_, err = part.Write(file.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest("POST", "/v1/gql/query", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+// 添付ファイルを取得する
+func getFile(r *gin.Engine, url string, header http.Header) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// 添付ファイルを扱うルーターを初期化する
+// SQLiteストアではファイルの内容をローカルディスクに保存する
+func attachmentRouter(t *testing.T, store models.Store) *gin.Engine {
+	t.Helper()
+
+	if _, ok := store.(*models.SQLiteStore); !ok {
+		return storeRouter(store)
+	}
+	blobs, err := models.NewDiskBlobStore(t.TempDir())
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	return routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(store), routers.WithBlobStore(blobs))
+}
+
+// PNGとPDFを添付した投稿を作成し、投稿IDと添付ファイルを返す
+func createAttachedPost(t *testing.T, r *gin.Engine) (string, []interface{}) {
+	t.Helper()
+
+	response := createPostWithFiles(t, r, uploadFile{`C:\写真\画像.png`, pngData}, uploadFile{"資料.pdf", pdfData})
+	require.Nil(t, response["errors"])
+	post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	attachments := post["attachments"].([]interface{})
+	require.Len(t, attachments, 2)
+	return post["id"].(string), attachments
+}
+
+// 内容から種類を判定し、ファイル名からディレクトリを取り除く
+func TestAttachments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := attachmentRouter(t, store)
+		id, attachments := createAttachedPost(t, r)
+
+		image := attachments[0].(map[string]interface{})
+		assert.Equal(t, "画像.png", image["filename"])
+		assert.Equal(t, "image/png", image["contentType"])
+		assert.Equal(t, float64(len(pngData)), image["size"])
+		assert.Equal(t, "/v1/files/"+image["id"].(string), image["url"])
+		assert.Equal(t, "application/pdf", attachments[1].(map[string]interface{})["contentType"])
+
+		// 投稿の取得でも添付ファイルを返す
+		response := doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { attachments { filename } } }`, id))
+		assert.Len(t, response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["attachments"], 2)
+
+		// 許可していない種類のファイルは拡張子によらず BAD_REQUEST
+		response = createPostWithFiles(t, r, uploadFile{"ok.png", pngData}, uploadFile{"evil.png", htmlData})
+		assert.Nil(t, response["data"])
+		assert.Equal(t, []string{"attachments.1"}, errorFields(t, response))
+	})
+}
+
+// 判定したMIMEタイプとキャッシュ用のヘッダーを付けて配信する
+func TestServeAttachment(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := attachmentRouter(t, store)
+		_, attachments := createAttachedPost(t, r)
+		image := attachments[0].(map[string]interface{})
+
+		w := getFile(r, image["url"].(string), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, pngData, w.Body.Bytes())
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "inline; filename*=utf-8''%E7%94%BB%E5%83%8F.png", w.Header().Get("Content-Disposition"))
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+		assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+		assert.Equal(t, http.StatusNotModified, getFile(r, image["url"].(string), http.Header{"If-None-Match": {etag}}).Code)
+
+		// 画像以外はダウンロードさせる
+		document := attachments[1].(map[string]interface{})
+		assert.Equal(t, "attachment; filename*=utf-8''%E8%B3%87%E6%96%99.pdf", getFile(r, document["url"].(string), nil).Header().Get("Content-Disposition"))
+	})
+}
+
+// 存在しない添付ファイルと、削除済みの投稿の添付ファイルは 404
+func TestAttachmentNotFound(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := attachmentRouter(t, store)
+		admin := adminToken(t, r)
+		id, attachments := createAttachedPost(t, r)
+
+		assert.Equal(t, http.StatusNotFound, getFile(r, "/v1/files/999", nil).Code)
+		assert.Equal(t, http.StatusNotFound, getFile(r, "/v1/files/abc", nil).Code)
+		doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: %q) }`, id))
+		assert.Equal(t, http.StatusNotFound, getFile(r, attachments[0].(map[string]interface{})["url"].(string), nil).Code)
+	})
+}
+
+// 添付ファイルの数と大きさの上限のテスト
+func TestAttachmentLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig()
+	cfg.MaxAttachments = 2
+	cfg.MaxAttachmentSize = len(pngData)
+	r := routers.SetupRouter(routers.WithConfig(cfg))
+
+	response := createPostWithFiles(t, r, uploadFile{"1.png", pngData}, uploadFile{"2.png", pngData}, uploadFile{"3.png", pngData})
+	assert.Equal(t, []string{"attachments"}, errorFields(t, response))
+
+	response = createPostWithFiles(t, r, uploadFile{"ok.png", pngData}, uploadFile{"large.pdf", pdfData})
+	assert.Equal(t, []string{"attachments.1"}, errorFields(t, response))
+
+	// 他の項目の誤りがあっても添付ファイルの誤りを返す
+	response = createPostWithTitle(t, r, "", uploadFile{"evil.png", htmlData})
+	assert.Equal(t, []string{"title", "attachments.0"}, errorFields(t, response))
+	response = createPostWithTitle(t, r, "", uploadFile{"1.png", pngData}, uploadFile{"2.png", pngData}, uploadFile{"3.png", pngData})
+	assert.Equal(t, []string{"title", "attachments"}, errorFields(t, response))
+}
+
+// 投稿の作成に必ず失敗する投稿の保存先
+type failingPostStore struct {
+	models.PostStore
+}
+
+// 投稿を作成せずにエラーを返す
+func (failingPostStore) Create(ctx context.Context, post *models.Post, attachments []models.Attachment) error {
+	return models.InternalServerError("failed to create post", "test")
+}
+
+// 投稿の作成に必ず失敗するストア
+type failingStore struct {
+	models.Store
+}
+
+// 投稿の作成に失敗する投稿の保存先を返す
+func (s failingStore) Posts() models.PostStore {
+	return failingPostStore{s.Store.Posts()}
+}
+
+// ファイルの内容が保存されているか
+func blobExists(t *testing.T, blobs models.BlobStore, data []byte) bool {
+	t.Helper()
+
+	sum := sha256.Sum256(data)
+	f, err := blobs.Open(context.Background(), hex.EncodeToString(sum[:]))
+	if models.IsKind(err, models.ErrorKindNotFound) {
+		return false
+	}
+	require.NoError(t, err)
+	f.Close()
+	return true
+}
+
+// 投稿の作成に失敗した場合は、他の添付ファイルが参照していないファイルの内容だけを削除する
+func TestAttachmentCleanup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := models.NewMemoryStore(models.SeedPosts)
+	blobs := models.NewMemoryBlobStore()
+	r := routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(store), routers.WithBlobStore(blobs))
+	failing := routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(failingStore{store}), routers.WithBlobStore(blobs))
+
+	response := createPostWithFiles(t, r, uploadFile{"画像.png", pngData})
+	require.Nil(t, response["errors"])
+	response = createPostWithFiles(t, failing, uploadFile{"画像.png", pngData}, uploadFile{"資料.pdf", pdfData})
+	_, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", ext["code"])
+
+	assert.True(t, blobExists(t, blobs, pngData))
+	assert.False(t, blobExists(t, blobs, pdfData))
+}
+
+// 完全に削除した投稿のファイルの内容は、他の投稿が参照していない場合だけ削除する
+func TestPurgeAttachments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		blobs := models.NewMemoryBlobStore()
+		r := routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(store), routers.WithBlobStore(blobs))
+		admin := adminToken(t, r)
+
+		response := createPostWithFiles(t, r, uploadFile{"画像.png", pngData})
+		require.Nil(t, response["errors"])
+		kept := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		response = createPostWithFiles(t, r, uploadFile{"画像.png", pngData}, uploadFile{"資料.pdf", pdfData})
+		require.Nil(t, response["errors"])
+		purged := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+
+		doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: "%s") }`, purged))
+		response = doQueryAs(t, r, admin, `mutation { purgeDeletedPosts(olderThan: "2100-01-01T00:00:00Z") }`)
+		require.Nil(t, response["errors"])
+		assert.True(t, blobExists(t, blobs, pngData))
+		assert.False(t, blobExists(t, blobs, pdfData))
+
+		// ゴミ箱の投稿の添付ファイルは元に戻せるよう、完全に削除するまで内容を残す
+		doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: "%s") }`, kept))
+		response = doQueryAs(t, r, admin, `mutation { purgeDeletedPosts(olderThan: "2000-01-01T00:00:00Z") }`)
+		require.Nil(t, response["errors"])
+		assert.True(t, blobExists(t, blobs, pngData))
+		response = doQueryAs(t, r, admin, `mutation { purgeDeletedPosts(olderThan: "2100-01-01T00:00:00Z") }`)
+		require.Nil(t, response["errors"])
+		assert.False(t, blobExists(t, blobs, pngData))
+	})
+}
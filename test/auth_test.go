@@ -0,0 +1,133 @@
+package resolver_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bbs-gql-project/config"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Authorization ヘッダーを付けてGraphQLクエリを実行し、ステータスコードとレスポンスを返す
+func doAuthQuery(t *testing.T, r *gin.Engine, authorization string, query string) (int, map[string]interface{}) {
+	t.Helper()
+
+	jsonValue, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	return w.Code, response
+}
+
+// ユーザーを登録してアクセストークンを返す
+func registerToken(t *testing.T, r *gin.Engine, username string) string {
+	t.Helper()
+
+	response := doQuery(t, r, `mutation { register(username: "`+username+`", password: "correct-horse") { token } }`)
+	assert.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["register"].(map[string]interface{})["token"].(string)
+}
+
+// Bearerトークンによる認証のテスト
+func TestBearerAuthentication(t *testing.T) {
+	r, _ := setupTestRouter()
+	token := registerToken(t, r, "taro")
+
+	// トークンのユーザーが viewer になる
+	status, response := doAuthQuery(t, r, "Bearer "+token, `query { viewer { username } }`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, response["errors"])
+	viewer := response["data"].(map[string]interface{})["viewer"].(map[string]interface{})
+	assert.Equal(t, "taro", viewer["username"])
+
+	// 投稿者が設定される
+	_, response = doAuthQuery(t, r, "Bearer "+token, `mutation { createPost(input: {title: "記名", content: "本文"}) { author { username } } }`)
+	assert.Nil(t, response["errors"])
+	post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	assert.Equal(t, "taro", post["author"].(map[string]interface{})["username"])
+
+	// トークンがない場合は viewer の取得がエラーになる
+	response = doQuery(t, r, `query { viewer { username } }`)
+	_, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+	assert.Equal(t, "TOKEN_MISSING", ext["reason"])
+}
+
+// 不正なトークンのテスト
+func TestInvalidTokens(t *testing.T) {
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	gin.SetMode(gin.TestMode)
+	r := routers.SetupRouter(routers.WithClock(func() time.Time { return now }))
+	token := registerToken(t, r, "taro")
+
+	cases := []struct {
+		name          string
+		authorization string
+		reason        string
+	}{
+		{"Bearer以外の形式", "Basic dGFybzpwYXNz", "TOKEN_MALFORMED"},
+		{"JWTではない", "Bearer abc", "TOKEN_MALFORMED"},
+		{"別の鍵で署名", "Bearer " + registerToken(t, setupRouterOnly(), "jiro"), "TOKEN_INVALID"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, response := doAuthQuery(t, r, tc.authorization, `query { viewer { username } }`)
+			assert.Equal(t, http.StatusUnauthorized, status)
+			_, ext := firstErrorExtensions(t, response)
+			assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+			assert.Equal(t, tc.reason, ext["reason"])
+		})
+	}
+
+	// 有効期限切れ
+	now = now.Add(config.DefaultTokenTTL + time.Second)
+	status, response := doAuthQuery(t, r, "Bearer "+token, `query { viewer { username } }`)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	_, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "TOKEN_EXPIRED", ext["reason"])
+}
+
+// EdDSAで署名したトークンのテスト
+func TestEdDSATokens(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.JWTAlgorithm = config.JWTAlgorithmEdDSA
+	cfg.JWTEd25519Key = key
+	r := routers.SetupRouter(routers.WithConfig(cfg))
+	token := registerToken(t, r, "taro")
+
+	status, response := doAuthQuery(t, r, "Bearer "+token, `query { viewer { username } }`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, response["errors"])
+
+	// HS256で署名したトークンは受け付けない
+	status, _ = doAuthQuery(t, r, "Bearer "+registerToken(t, setupRouterOnly(), "jiro"), `query { viewer { username } }`)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+// 既定の設定でルーターのみを作成する
+func setupRouterOnly() *gin.Engine {
+	r, _ := setupTestRouter()
+	return r
+}
@@ -0,0 +1,141 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 掲示板のスラッグ一覧を取得する
+func boardSlugs(t *testing.T, r *gin.Engine, query string) []string {
+	t.Helper()
+
+	response := doQuery(t, r, query)
+	slugs := []string{}
+	for _, b := range response["data"].(map[string]interface{})["boards"].([]interface{}) {
+		slugs = append(slugs, b.(map[string]interface{})["slug"].(string))
+	}
+	return slugs
+}
+
+// 管理者として掲示板 news(スレッドは2件、本文は10文字まで)を作成し、管理者のトークンを返す
+func createNewsBoard(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+
+	admin := adminToken(t, r)
+	response := doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "news", name: "ニュース", maxThreads: 2, maxPostLength: 10}) { slug } }`)
+	assert.Nil(t, response["errors"])
+	return admin
+}
+
+// 既定の掲示板にサンプルデータが属している
+func TestDefaultBoard(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		assert.Equal(t, []string{"general"}, boardSlugs(t, r, `query { boards { slug } }`))
+		response := doQuery(t, r, `query { getPost(id: "1") { board { slug name } } }`)
+		board := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["board"].(map[string]interface{})
+		assert.Equal(t, "general", board["slug"])
+		response = doQuery(t, r, `query { board(slug: "general") { threads { totalCount } } }`)
+		assert.Equal(t, float64(10), response["data"].(map[string]interface{})["board"].(map[string]interface{})["threads"].(map[string]interface{})["totalCount"])
+	})
+}
+
+// 掲示板の作成は管理者のみで、スラッグの重複と形式を検証する
+func TestCreateBoard(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		member := registerToken(t, r, "taro")
+
+		create := `mutation { createBoard(input: {slug: "news", name: "ニュース", maxThreads: 2, maxPostLength: 10}) { slug name maxThreads maxPostLength archived } }`
+		response := doQueryAs(t, r, member, create)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		response = doQueryAs(t, r, admin, create)
+		assert.Nil(t, response["errors"])
+		board := response["data"].(map[string]interface{})["createBoard"].(map[string]interface{})
+		assert.Equal(t, float64(2), board["maxThreads"])
+		assert.Equal(t, false, board["archived"])
+
+		response = doQueryAs(t, r, admin, create)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "CONFLICT", ext["code"])
+		response = doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "News!", name: "", maxThreads: -1}) { slug } }`)
+		assert.Equal(t, []string{"slug", "name", "maxThreads"}, errorFields(t, response))
+	})
+}
+
+// 掲示板を指定した投稿と、掲示板ごとのスレッド一覧
+func TestBoardThreads(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		createNewsBoard(t, r)
+
+		// 本文は掲示板の上限で検証される
+		response := doQuery(t, r, `mutation { createPost(input: {title: "長い", content: "10文字を超える本文です", boardSlug: "news"}) { id } }`)
+		assert.Equal(t, []string{"content"}, errorFields(t, response))
+		response = doQuery(t, r, `mutation { createPost(input: {title: "速報", content: "本文", boardSlug: "news"}) { board { slug } } }`)
+		assert.Equal(t, "news", response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["board"].(map[string]interface{})["slug"])
+		doQuery(t, r, `mutation { createPost(input: {title: "続報", content: "本文", boardSlug: "news"}) { id } }`)
+
+		// スレッド数の上限を超えると、最も古いスレッドがアーカイブされる
+		response = doQuery(t, r, `mutation { createPost(input: {title: "第三報", content: "本文", boardSlug: "news"}) { id } }`)
+		assert.Nil(t, response["errors"])
+
+		response = doQuery(t, r, `query { board(slug: "news") { threads(first: 1) { totalCount pageInfo { hasNextPage } edges { node { title } } } } }`)
+		threads := response["data"].(map[string]interface{})["board"].(map[string]interface{})["threads"].(map[string]interface{})
+		assert.Equal(t, float64(2), threads["totalCount"])
+		assert.Equal(t, true, threads["pageInfo"].(map[string]interface{})["hasNextPage"])
+		assert.Equal(t, "続報", threads["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})["title"])
+
+		// 存在しない掲示板
+		response = doQuery(t, r, `mutation { createPost(input: {title: "x", content: "x", boardSlug: "nothing"}) { id } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
+
+// 返信の本文もスレッドが属する掲示板の上限で検証される
+func TestBoardReplyLength(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := createNewsBoard(t, r)
+
+		response := doQuery(t, r, `mutation { createPost(input: {title: "速報", content: "本文", boardSlug: "news"}) { id } }`)
+		id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		response = doQuery(t, r, fmt.Sprintf(`mutation { createComment(input: {postId: %q, content: "10文字を超える返信です"}) { id } }`, id))
+		assert.Equal(t, []string{"content"}, errorFields(t, response))
+
+		comment := createComment(t, r, id, "", "短い返信")
+		response = doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updateComment(id: %q, input: {content: "10文字を超える返信です"}) { id } }`, comment))
+		assert.Equal(t, []string{"content"}, errorFields(t, response))
+	})
+}
+
+// 掲示板の名前の変更とアーカイブ
+func TestArchiveBoard(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := createNewsBoard(t, r)
+
+		response := doQueryAs(t, r, admin, `mutation { renameBoard(slug: "news", name: "速報") { name } }`)
+		assert.Equal(t, "速報", response["data"].(map[string]interface{})["renameBoard"].(map[string]interface{})["name"])
+		response = doQueryAs(t, r, admin, `mutation { archiveBoard(slug: "news") { archived } }`)
+		assert.Equal(t, true, response["data"].(map[string]interface{})["archiveBoard"].(map[string]interface{})["archived"])
+		assert.Equal(t, []string{"general"}, boardSlugs(t, r, `query { boards { slug } }`))
+		assert.Equal(t, []string{"general", "news"}, boardSlugs(t, r, `query { boards(includeArchived: true) { slug } }`))
+
+		// アーカイブ済みの掲示板には投稿できないが、スレッドは閲覧できる
+		response = doQuery(t, r, `mutation { createPost(input: {title: "x", content: "x", boardSlug: "news"}) { id } }`)
+		message, _ := firstErrorExtensions(t, response)
+		assert.Equal(t, "board is archived", message)
+		response = doQuery(t, r, `query { board(slug: "news") { name threads { totalCount } } }`)
+		assert.Nil(t, response["errors"])
+	})
+}
@@ -0,0 +1,118 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// スレッド一覧のタイトルとページ情報を取得する
+func threadTitles(t *testing.T, r *gin.Engine, args string) ([]string, map[string]interface{}) {
+	t.Helper()
+
+	response := doQuery(t, r, `query { board(slug: "bump") { threads(`+args+`) { pageInfo { hasNextPage endCursor } edges { node { title } } } } }`)
+	require.Nil(t, response["errors"])
+	threads := response["data"].(map[string]interface{})["board"].(map[string]interface{})["threads"].(map[string]interface{})
+	titles := []string{}
+	for _, edge := range threads["edges"].([]interface{}) {
+		titles = append(titles, edge.(map[string]interface{})["node"].(map[string]interface{})["title"].(string))
+	}
+	return titles, threads["pageInfo"].(map[string]interface{})
+}
+
+// 掲示板 bump にスレッド A・B・C を1分おきに作成し、スレッドに返信する関数を返す
+// advance は時計を1分進める
+func createBumpThreads(t *testing.T, r *gin.Engine, advance func()) func(title string, sage bool) {
+	t.Helper()
+
+	admin := adminToken(t, r)
+	doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "bump", name: "上げ"}) { id } }`)
+
+	ids := map[string]string{}
+	for _, title := range []string{"A", "B", "C"} {
+		advance()
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: %q, content: "本文", boardSlug: "bump"}) { id } }`, title))
+		ids[title] = response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+	}
+	return func(title string, sage bool) {
+		t.Helper()
+		advance()
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createComment(input: {postId: %q, content: "返信", sage: %t}) { sage } }`, ids[title], sage))
+		assert.Equal(t, sage, response["data"].(map[string]interface{})["createComment"].(map[string]interface{})["sage"])
+	}
+}
+
+// 上げ制限を2件にした設定で、時計を指定してルーターを作成する
+func bumpRouter(store models.Store) (*gin.Engine, func()) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	cfg := testConfig()
+	cfg.BumpLimit = 2
+	r := routers.SetupRouter(routers.WithConfig(cfg), routers.WithStore(store), routers.WithClock(func() time.Time { return now }))
+	return r, func() { now = now.Add(time.Minute) }
+}
+
+// 返信によるスレッドの上げ・sage・上げ制限のテスト
+func TestThreadBumping(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		reply := createBumpThreads(t, r, advance)
+
+		titles, _ := threadTitles(t, r, `orderBy: BUMP`)
+		assert.Equal(t, []string{"C", "B", "A"}, titles)
+
+		// 返信するとスレッドが上がる
+		reply("A", false)
+		titles, _ = threadTitles(t, r, `orderBy: BUMP`)
+		assert.Equal(t, []string{"A", "C", "B"}, titles)
+
+		// sageの返信ではスレッドが上がらない
+		reply("B", true)
+		titles, _ = threadTitles(t, r, `orderBy: BUMP`)
+		assert.Equal(t, []string{"A", "C", "B"}, titles)
+
+		// 上げ制限(2件)までの返信はスレッドを上げる
+		reply("C", false)
+		reply("A", false)
+		reply("C", false)
+		titles, _ = threadTitles(t, r, `orderBy: BUMP`)
+		assert.Equal(t, []string{"C", "A", "B"}, titles)
+
+		// 上げ制限を超えた返信ではスレッドが上がらない
+		reply("A", false)
+		titles, _ = threadTitles(t, r, `orderBy: BUMP`)
+		assert.Equal(t, []string{"C", "A", "B"}, titles)
+	})
+}
+
+// スレッド一覧の並び順とページネーションのテスト
+func TestThreadOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		reply := createBumpThreads(t, r, advance)
+		reply("A", false)
+
+		titles, pageInfo := threadTitles(t, r, `orderBy: BUMP, first: 2`)
+		assert.Equal(t, []string{"A", "C"}, titles)
+		assert.Equal(t, true, pageInfo["hasNextPage"])
+		titles, pageInfo = threadTitles(t, r, fmt.Sprintf(`orderBy: BUMP, first: 2, after: %q`, pageInfo["endCursor"]))
+		assert.Equal(t, []string{"B"}, titles)
+		assert.Equal(t, false, pageInfo["hasNextPage"])
+
+		// 作成順
+		titles, _ = threadTitles(t, r, `orderBy: CREATED`)
+		assert.Equal(t, []string{"A", "B", "C"}, titles)
+
+		// 上げ順では後方向のページネーションは使えない
+		response := doQuery(t, r, `query { board(slug: "bump") { threads(orderBy: BUMP, last: 2) { totalCount } } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "BAD_REQUEST", ext["code"])
+	})
+}
@@ -0,0 +1,170 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 未ログインでコメントを作成し、IDを返す
+func createComment(t *testing.T, r *gin.Engine, postID string, parentID string, content string) string {
+	t.Helper()
+	return createCommentAs(t, r, "", postID, parentID, content)
+}
+
+// アクセストークンを指定してコメントを作成し、IDを返す
+func createCommentAs(t *testing.T, r *gin.Engine, token string, postID string, parentID string, content string) string {
+	t.Helper()
+
+	parent := ""
+	if parentID != "" {
+		parent = fmt.Sprintf(`, parentId: %q`, parentID)
+	}
+	response := doQueryAs(t, r, token, fmt.Sprintf(`mutation { createComment(input: {postId: %q%s, content: %q}) { id postId parentId content createdAt } }`, postID, parent, content))
+	assert.Nil(t, response["errors"])
+
+	comment := response["data"].(map[string]interface{})["createComment"].(map[string]interface{})
+	assert.Equal(t, postID, comment["postId"])
+	assert.Equal(t, content, comment["content"])
+	assert.NotEmpty(t, comment["createdAt"])
+	return comment["id"].(string)
+}
+
+// 投稿2に3段の返信と2つ目のコメントを作成し、入れ子のコメントのIDを浅い順に返す
+// token が空の場合は未ログインで作成する
+func createCommentThread(t *testing.T, r *gin.Engine, token string) (string, string, string) {
+	t.Helper()
+
+	top := createCommentAs(t, r, token, "2", "", "最初のコメント")
+	reply := createCommentAs(t, r, token, "2", top, "返信")
+	nested := createCommentAs(t, r, token, "2", reply, "返信への返信")
+	createCommentAs(t, r, token, "2", "", "2つ目のコメント")
+	return top, reply, nested
+}
+
+// 入れ子になった返信を取得できる
+func TestCommentThreads(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		top, reply, nested := createCommentThread(t, r, "")
+
+		response := doQuery(t, r, `query {
+			getPost(id: "2") {
+				comments(first: 1) {
+					totalCount
+					pageInfo { hasNextPage }
+					edges { node { id parentId replies { edges { node { id parentId replies { edges { node { id content } } } } } } } }
+				}
+			}
+		}`)
+		comments := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["comments"].(map[string]interface{})
+		assert.Equal(t, float64(2), comments["totalCount"])
+		assert.Equal(t, true, comments["pageInfo"].(map[string]interface{})["hasNextPage"])
+
+		first := comments["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})
+		assert.Equal(t, top, first["id"])
+		assert.Nil(t, first["parentId"])
+		second := first["replies"].(map[string]interface{})["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})
+		assert.Equal(t, reply, second["id"])
+		assert.Equal(t, top, second["parentId"])
+		third := second["replies"].(map[string]interface{})["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})
+		assert.Equal(t, nested, third["id"])
+		assert.Equal(t, "返信への返信", third["content"])
+	})
+}
+
+// コメントの更新はコメントした本人または MODERATOR 以上のみ
+func TestUpdateComment(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		taro := registerToken(t, r, "taro")
+		jiro := registerToken(t, r, "jiro")
+		_, reply, _ := createCommentThread(t, r, taro)
+		anonymous := createComment(t, r, "2", "", "匿名のコメント")
+
+		update := func(token string, id string) map[string]interface{} {
+			return doQueryAs(t, r, token, fmt.Sprintf(`mutation { updateComment(id: %q, input: {content: "編集済み"}) { content author { username } } }`, id))
+		}
+		_, ext := firstErrorExtensions(t, update("", reply))
+		assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+		_, ext = firstErrorExtensions(t, update(jiro, reply))
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		_, ext = firstErrorExtensions(t, update(taro, anonymous))
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+
+		response := update(taro, reply)
+		comment := response["data"].(map[string]interface{})["updateComment"].(map[string]interface{})
+		assert.Equal(t, "編集済み", comment["content"])
+		assert.Equal(t, "taro", comment["author"].(map[string]interface{})["username"])
+		response = update(adminToken(t, r), anonymous)
+		comment = response["data"].(map[string]interface{})["updateComment"].(map[string]interface{})
+		assert.Equal(t, "編集済み", comment["content"])
+		assert.Nil(t, comment["author"])
+	})
+}
+
+// アーカイブ済みのスレッドのコメントは編集できない
+func TestUpdateArchivedComment(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		taro := registerToken(t, r, "taro")
+		response := doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "one", name: "1スレッド", maxThreads: 1}) { id } }`)
+		require.Nil(t, response["errors"])
+
+		response = doQuery(t, r, `mutation { createPost(input: {title: "古いスレッド", content: "本文", boardSlug: "one"}) { id } }`)
+		id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		comment := createCommentAs(t, r, taro, id, "", "返信")
+		response = doQuery(t, r, `mutation { createPost(input: {title: "新しいスレッド", content: "本文", boardSlug: "one"}) { id } }`)
+		require.Nil(t, response["errors"])
+
+		response = doQueryAs(t, r, taro, fmt.Sprintf(`mutation { updateComment(id: %q, input: {content: "編集済み"}) { id } }`, comment))
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "THREAD_ARCHIVED", ext["code"])
+	})
+}
+
+// 別の投稿のコメントへの返信と、存在しない投稿へのコメントはできない
+func TestCreateCommentErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		top := createComment(t, r, "2", "", "最初のコメント")
+
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createComment(input: {postId: "3", parentId: %q, content: "x"}) { id } }`, top))
+		assert.NotEmpty(t, response["errors"])
+		response = doQuery(t, r, `mutation { createComment(input: {postId: "999", content: "x"}) { id } }`)
+		assert.NotEmpty(t, response["errors"])
+	})
+}
+
+// コメントを削除すると返信も削除され、投稿を削除するとコメントも見つからなくなる
+// 削除はコメントした本人または MODERATOR 以上のみ
+func TestDeleteComment(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		taro := registerToken(t, r, "taro")
+		top, reply, nested := createCommentThread(t, r, taro)
+
+		_, ext := firstErrorExtensions(t, doQuery(t, r, fmt.Sprintf(`mutation { deleteComment(id: %q) }`, reply)))
+		assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+		_, ext = firstErrorExtensions(t, doQueryAs(t, r, registerToken(t, r, "jiro"), fmt.Sprintf(`mutation { deleteComment(id: %q) }`, reply)))
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+
+		response := doQueryAs(t, r, taro, fmt.Sprintf(`mutation { deleteComment(id: %q) }`, reply))
+		assert.True(t, response["data"].(map[string]interface{})["deleteComment"].(bool))
+		response = doQueryAs(t, r, taro, fmt.Sprintf(`mutation { deleteComment(id: %q) }`, nested))
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+
+		admin := adminToken(t, r)
+		doQueryAs(t, r, admin, `mutation { deletePost(id: "2") }`)
+		response = doQueryAs(t, r, taro, fmt.Sprintf(`mutation { updateComment(id: %q, input: {content: "x"}) { id } }`, top))
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
@@ -0,0 +1,63 @@
+package resolver_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 削除後に作成した投稿がIDを再利用しないことのテスト
+func TestCreateAfterDeleteDoesNotReuseID(t *testing.T) {
+	r, _ := setupTestRouter()
+	admin := adminToken(t, r)
+
+	doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+	response := doQuery(t, r, `mutation { createPost(input: {title: "新規", content: "新規投稿"}) { id } }`)
+
+	created := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	assert.Equal(t, "11", created["id"])
+}
+
+// createPost と deletePost を並行に実行するテスト
+// go test -race で実行し、データ競合が起きないことを確認する
+func TestConcurrentCreateAndDelete(t *testing.T) {
+	r, _ := setupTestRouter()
+	admin := adminToken(t, r)
+
+	const workers = 8
+	const perWorker = 25
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				query := fmt.Sprintf(`mutation { createPost(input: {title: "w%d-%d", content: "並行投稿"}) { id } }`, worker, j)
+				response := doQuery(t, r, query)
+				created := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+				id := created["id"].(string)
+
+				mu.Lock()
+				assert.False(t, seen[id], "ID %s was allocated twice", id)
+				seen[id] = true
+				mu.Unlock()
+
+				// 作成した投稿を半分削除し、削除と作成を交互に走らせる
+				if j%2 == 0 {
+					response = doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: "%s") }`, id))
+					assert.True(t, response["data"].(map[string]interface{})["deletePost"].(bool))
+				}
+				doQuery(t, r, `query { getAllPosts(page: 1, per_page: 20) { id } }`)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, workers*perWorker)
+}
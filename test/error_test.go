@@ -0,0 +1,86 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	"bbs-gql-project/config"
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 投稿の取得時にパニックを起こすストア
+type panicStore struct {
+	*models.MemoryStore
+}
+
+func (s panicStore) Posts() models.PostStore {
+	return panicPostStore{s.MemoryStore.Posts()}
+}
+
+type panicPostStore struct {
+	models.PostStore
+}
+
+func (panicPostStore) Get(ctx context.Context, id int) (*models.Post, error) {
+	panic("storage is broken")
+}
+
+// レスポンスから最初のエラーの extensions を取り出す
+func firstErrorExtensions(t *testing.T, response map[string]interface{}) (string, map[string]interface{}) {
+	t.Helper()
+
+	errs := response["errors"].([]interface{})
+	assert.Len(t, errs, 1)
+	first := errs[0].(map[string]interface{})
+	return first["message"].(string), first["extensions"].(map[string]interface{})
+}
+
+// AppErrorが extensions に変換されることのテスト
+func TestErrorExtensions(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	response := doQuery(t, r, `query { getPost(id: "999") { id } }`)
+	message, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "post not found", message)
+	assert.Equal(t, "NOT_FOUND", ext["code"])
+	assert.Equal(t, float64(404), ext["httpStatus"])
+	assert.Equal(t, "post not found", ext["detail"])
+
+	response = doQuery(t, r, `query { getPost(id: "abc") { id } }`)
+	_, ext = firstErrorExtensions(t, response)
+	assert.Equal(t, "BAD_REQUEST", ext["code"])
+	assert.Equal(t, float64(400), ext["httpStatus"])
+}
+
+// パニックが 500 エラーに変換されることのテスト
+func TestPanicBecomesInternalServerError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := panicStore{models.NewMemoryStore(models.SeedPosts)}
+
+	// 開発環境では詳細を出力する
+	r := routers.SetupRouter(routers.WithStore(store))
+	response := doQuery(t, r, `query { getPost(id: "1") { id } }`)
+	message, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "internal server error", message)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", ext["code"])
+	assert.Equal(t, float64(500), ext["httpStatus"])
+	assert.Equal(t, "storage is broken", ext["detail"])
+
+	// 本番環境では 500 エラーの詳細を隠す
+	cfg := config.Default()
+	cfg.Production = true
+	r = routers.SetupRouter(routers.WithConfig(cfg), routers.WithStore(store))
+	response = doQuery(t, r, `query { getPost(id: "1") { id } }`)
+	_, ext = firstErrorExtensions(t, response)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", ext["code"])
+	assert.NotContains(t, ext, "detail")
+
+	// 本番環境でも 4xx エラーの詳細は出力する
+	response = doQuery(t, r, `query { getPost(id: "abc") { id } }`)
+	_, ext = firstErrorExtensions(t, response)
+	assert.Equal(t, "invalid ID format", ext["detail"])
+}
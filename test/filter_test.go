@@ -0,0 +1,170 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 絞り込み条件と並び順を指定した投稿一覧のタイトルを取得する
+func filteredTitles(t *testing.T, r *gin.Engine, args string) []string {
+	t.Helper()
+
+	response := doQuery(t, r, `query { getAllPosts(page: 1, per_page: 100, `+args+`) { title } }`)
+	require.Nil(t, response["errors"], args)
+	titles := []string{}
+	for _, post := range response["data"].(map[string]interface{})["getAllPosts"].([]interface{}) {
+		titles = append(titles, post.(map[string]interface{})["title"].(string))
+	}
+	return titles
+}
+
+// カーソルページネーションの投稿一覧をすべてのページを辿って取得し、タイトルを返す
+// last を指定した場合は末尾から before で前のページを辿る
+func pagedTitles(t *testing.T, r *gin.Engine, orderBy string, last bool) []string {
+	t.Helper()
+
+	titles := []string{}
+	cursor := ""
+	for {
+		args := "first: 3, orderBy: " + orderBy
+		if last {
+			args = "last: 3, orderBy: " + orderBy
+		}
+		if cursor != "" && last {
+			args += fmt.Sprintf(", before: %q", cursor)
+		} else if cursor != "" {
+			args += fmt.Sprintf(", after: %q", cursor)
+		}
+		response := doQuery(t, r, `query { posts(`+args+`) { edges { node { title } } pageInfo { startCursor endCursor hasNextPage hasPreviousPage } } }`)
+		require.Nil(t, response["errors"], args)
+		connection := response["data"].(map[string]interface{})["posts"].(map[string]interface{})
+		page := []string{}
+		for _, edge := range connection["edges"].([]interface{}) {
+			page = append(page, edge.(map[string]interface{})["node"].(map[string]interface{})["title"].(string))
+		}
+		pageInfo := connection["pageInfo"].(map[string]interface{})
+		if last {
+			titles = append(page, titles...)
+			assert.Equal(t, cursor != "", pageInfo["hasNextPage"], args)
+			if pageInfo["hasPreviousPage"] != true {
+				return titles
+			}
+			cursor = pageInfo["startCursor"].(string)
+		} else {
+			titles = append(titles, page...)
+			assert.Equal(t, cursor != "", pageInfo["hasPreviousPage"], args)
+			if pageInfo["hasNextPage"] != true {
+				return titles
+			}
+			cursor = pageInfo["endCursor"].(string)
+		}
+	}
+}
+
+// 掲示板 news と、絞り込み用の投稿を1分おきに作成し、投稿者 taro のユーザーIDを返す
+// advance は時計を1分進める
+func createFilterPosts(t *testing.T, r *gin.Engine, advance func()) string {
+	t.Helper()
+
+	admin := adminToken(t, r)
+	doQueryAs(t, r, admin, `mutation { createBoard(input: {slug: "news", name: "ニュース"}) { id } }`)
+	taro := registerToken(t, r, "taro")
+	response := doQueryAs(t, r, taro, `query { viewer { id } }`)
+	taroID := response["data"].(map[string]interface{})["viewer"].(map[string]interface{})["id"].(string)
+
+	for _, post := range []struct{ token, input string }{
+		{taro, `{title: "Alpha news", content: "first body"}`},
+		{taro, `{title: "beta", content: "Second BODY"}`},
+		{"", `{title: "ガンマ", content: "日本語の本文", boardSlug: "news"}`},
+	} {
+		advance()
+		response := doQueryAs(t, r, post.token, `mutation { createPost(input: `+post.input+`) { id } }`)
+		require.Nil(t, response["errors"])
+	}
+	doQuery(t, r, `mutation { createComment(input: {postId: "1", content: "返信"}) { id } }`)
+	return taroID
+}
+
+// 投稿一覧の絞り込みのテスト
+func TestPostFilter(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		taroID := createFilterPosts(t, r, advance)
+
+		// 文字列の部分一致(英字の大文字・小文字は区別しない)
+		assert.Equal(t, []string{"Alpha news"}, filteredTitles(t, r, `filter: {titleContains: "ALPHA"}`))
+		assert.Equal(t, []string{"Alpha news", "beta"}, filteredTitles(t, r, `filter: {contentContains: "body"}`))
+
+		// 投稿者・掲示板・作成日時・コメントの有無
+		assert.Equal(t, []string{"Alpha news", "beta"}, filteredTitles(t, r, fmt.Sprintf(`filter: {authorId: %q}`, taroID)))
+		assert.Equal(t, []string{"ガンマ"}, filteredTitles(t, r, `filter: {board: "news"}`))
+		assert.Equal(t, []string{"Alpha news", "beta", "ガンマ"}, filteredTitles(t, r, `filter: {createdAfter: "2024-12-01T00:00:00Z"}`))
+		assert.Equal(t, []string{"投稿1", "投稿2"}, filteredTitles(t, r, `filter: {createdBefore: "2024-10-01T09:03:00Z"}`))
+		assert.Equal(t, []string{"投稿1"}, filteredTitles(t, r, `filter: {hasReplies: true}`))
+		assert.Len(t, filteredTitles(t, r, `filter: {hasReplies: false}`), 12)
+		assert.Equal(t, []string{"Alpha news", "beta"}, filteredTitles(t, r, `filter: {contentContains: "body", createdAfter: "2024-12-01T00:00:00Z", hasReplies: false}`))
+
+		// カーソルページネーションの一覧でも同じ条件で絞り込める
+		response := doQuery(t, r, `query { posts(filter: {contentContains: "body"}) { totalCount edges { node { title } } } }`)
+		assert.Equal(t, float64(2), response["data"].(map[string]interface{})["posts"].(map[string]interface{})["totalCount"])
+	})
+}
+
+// 投稿一覧の並び順のテスト
+func TestPostOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		createFilterPosts(t, r, advance)
+
+		assert.Equal(t, []string{"ガンマ", "beta", "Alpha news"}, filteredTitles(t, r, `filter: {createdAfter: "2024-12-01T00:00:00Z"}, orderBy: {field: TITLE, direction: DESC}`))
+		assert.Equal(t, "ガンマ", filteredTitles(t, r, `orderBy: {field: CREATED_AT, direction: DESC}`)[0])
+		response := doQuery(t, r, `query { getAllPosts(page: 2, per_page: 2, orderBy: {field: CREATED_AT, direction: DESC}) { title } }`)
+		posts := response["data"].(map[string]interface{})["getAllPosts"].([]interface{})
+		assert.Equal(t, "Alpha news", posts[0].(map[string]interface{})["title"])
+		assert.Equal(t, "投稿10", posts[1].(map[string]interface{})["title"])
+	})
+}
+
+// カーソルページネーションの一覧の並び順のテスト
+// 前後どちらのページを辿っても getAllPosts と同じ順になる
+func TestPostsOrderPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r, advance := bumpRouter(store)
+		createFilterPosts(t, r, advance)
+
+		for _, orderBy := range []string{`{field: TITLE, direction: DESC}`, `{field: CREATED_AT, direction: DESC}`, `{field: LAST_BUMPED_AT}`, `{field: ID, direction: DESC}`} {
+			expected := filteredTitles(t, r, "orderBy: "+orderBy)
+			assert.Equal(t, expected, pagedTitles(t, r, orderBy, false), orderBy)
+			assert.Equal(t, expected, pagedTitles(t, r, orderBy, true), orderBy)
+		}
+
+		// 別の並び順で取得したカーソルは使えない
+		response := doQuery(t, r, `query { posts(first: 1, orderBy: {field: TITLE}) { pageInfo { endCursor } } }`)
+		cursor := response["data"].(map[string]interface{})["posts"].(map[string]interface{})["pageInfo"].(map[string]interface{})["endCursor"].(string)
+		response = doQuery(t, r, fmt.Sprintf(`query { posts(first: 1, after: %q, orderBy: {field: TITLE, direction: DESC}) { totalCount } }`, cursor))
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "BAD_REQUEST", ext["code"])
+		response = doQuery(t, r, fmt.Sprintf(`query { posts(first: 1, after: %q) { totalCount } }`, cursor))
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "BAD_REQUEST", ext["code"])
+	})
+}
+
+// 誤った絞り込み条件は BAD_REQUEST(存在しない掲示板の場合は指定できるスラッグを示す)
+func TestPostFilterErrors(t *testing.T) {
+	r, _ := setupTestRouter()
+	doQueryAs(t, r, adminToken(t, r), `mutation { createBoard(input: {slug: "news", name: "ニュース"}) { id } }`)
+
+	response := doQuery(t, r, `query { getAllPosts(page: 1, per_page: 10, filter: {board: "nothing"}) { id } }`)
+	assert.Equal(t, []string{"filter.board"}, errorFields(t, response))
+	_, ext := firstErrorExtensions(t, response)
+	assert.Contains(t, ext["detail"], "must be one of: general, news")
+	response = doQuery(t, r, `query { posts(filter: {createdAfter: "2024-12-02T00:00:00Z", createdBefore: "2024-12-01T00:00:00Z", authorId: "x", titleContains: ""}) { totalCount } }`)
+	assert.Equal(t, []string{"filter.titleContains", "filter.createdBefore", "filter.authorId"}, errorFields(t, response))
+}
@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"bbs-gql-project/config"
+	"bbs-gql-project/models"
 	"bbs-gql-project/routers"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +22,7 @@ func setupTestRouter() (*gin.Engine, *httptest.ResponseRecorder) {
 	gin.SetMode(gin.TestMode)
 
 	// ルーターを初期化
-	r := routers.SetupRouter()
+	r := routers.SetupRouter(routers.WithConfig(testConfig()))
 
 	// テスト用のレスポンスレコーダーを作成
 	w := httptest.NewRecorder()
@@ -27,6 +30,57 @@ func setupTestRouter() (*gin.Engine, *httptest.ResponseRecorder) {
 	return r, w
 }
 
+// テストで起動時に作成する管理者のユーザー名とパスワード
+const (
+	testAdminUsername = "admin"
+	testAdminPassword = "admin-password"
+)
+
+// テスト用の設定を返す
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.AdminUsername = testAdminUsername
+	cfg.AdminPassword = testAdminPassword
+	return cfg
+}
+
+// 管理者ユーザーでログインしてアクセストークンを返す
+func adminToken(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+
+	response := doQuery(t, r, `mutation { login(username: "`+testAdminUsername+`", password: "`+testAdminPassword+`") { token } }`)
+	assert.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["login"].(map[string]interface{})["token"].(string)
+}
+
+// GraphQLのクエリを送信し、レスポンスをデコードして返す
+func doQuery(t *testing.T, r *gin.Engine, query string) map[string]interface{} {
+	t.Helper()
+	return doQueryAs(t, r, "", query)
+}
+
+// アクセストークンを指定してGraphQLのクエリを送信し、レスポンスをデコードして返す
+// token が空の場合は未ログインとして送信する
+func doQueryAs(t *testing.T, r *gin.Engine, token string, query string) map[string]interface{} {
+	t.Helper()
+
+	jsonValue, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	return response
+}
+
 // 投稿作成のテスト
 func TestCreatePost(t *testing.T) {
 	r, w := setupTestRouter()
@@ -160,6 +214,7 @@ func TestUpdatePost(t *testing.T) {
 	jsonValue, _ := json.Marshal(updatePost)
 	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken(t, r))
 
 	r.ServeHTTP(w, req)
 
@@ -193,6 +248,7 @@ func TestDeletePost(t *testing.T) {
 	jsonValue, _ := json.Marshal(deletePost)
 	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken(t, r))
 
 	r.ServeHTTP(w, req)
 
@@ -207,3 +263,100 @@ func TestDeletePost(t *testing.T) {
 	data := response["data"].(map[string]interface{})
 	assert.True(t, data["deletePost"].(bool))
 }
+
+// ストアを差し替えた場合のテスト
+func TestGetPostWithInjectedStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := models.NewMemoryStore([]models.Post{
+		{ID: 1, Title: "差し替え", Content: "差し替えたストアの投稿"},
+	})
+	r := routers.SetupRouter(routers.WithStore(store))
+	w := httptest.NewRecorder()
+
+	query := map[string]interface{}{
+		"query": `
+			query {
+				getPost(id: "1") {
+					id
+					title
+					content
+				}
+			}
+		`,
+	}
+
+	jsonValue, _ := json.Marshal(query)
+	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	// ステータスコードの確認
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// レスポンスの内容を確認
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+
+	data := response["data"].(map[string]interface{})
+	post := data["getPost"].(map[string]interface{})
+	assert.Equal(t, "差し替え", post["title"])
+	assert.Equal(t, "差し替えたストアの投稿", post["content"])
+}
+
+// 投稿日時・更新日時のテスト
+func TestPostTimestamps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// テスト用の時計
+	now := time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC)
+	r := routers.SetupRouter(routers.WithClock(func() time.Time { return now }))
+	token := registerToken(t, r, "taro")
+
+	response := doQueryAs(t, r, token, `mutation { createPost(input: {title: "時刻", content: "日時のテスト"}) { id createdAt updatedAt } }`)
+	created := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	assert.Equal(t, "2024-12-24T18:00:00Z", created["createdAt"])
+	assert.Equal(t, "2024-12-24T18:00:00Z", created["updatedAt"])
+
+	// 投稿者自身が1時間後に更新する
+	now = now.Add(time.Hour)
+	response = doQueryAs(t, r, token, `mutation { updatePost(id: "11", input: {title: "時刻", content: "更新"}) { createdAt updatedAt } }`)
+	updated := response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+	assert.Equal(t, "2024-12-24T18:00:00Z", updated["createdAt"])
+	assert.Equal(t, "2024-12-24T19:00:00Z", updated["updatedAt"])
+
+	// サンプルデータにも日時が設定されている
+	response = doQuery(t, r, `query { getPost(id: "5") { createdAt updatedAt } }`)
+	post := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+	assert.Equal(t, "2024-10-01T09:05:00Z", post["createdAt"])
+	assert.Equal(t, "2024-10-01T09:05:00Z", post["updatedAt"])
+}
+
+// 投稿の部分更新のテスト
+func TestPartialUpdatePost(t *testing.T) {
+	r, _ := setupTestRouter()
+	admin := adminToken(t, r)
+
+	// タイトルだけを更新する
+	response := doQueryAs(t, r, admin, `mutation { updatePost(id: "2", input: {title: "誤字を修正"}) { title content } }`)
+	post := response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+	assert.Equal(t, "誤字を修正", post["title"])
+	assert.Equal(t, "サンプル投稿2", post["content"])
+
+	// 本文だけを更新する
+	response = doQueryAs(t, r, admin, `mutation { updatePost(id: "2", input: {content: "本文を修正"}) { title content } }`)
+	post = response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+	assert.Equal(t, "誤字を修正", post["title"])
+	assert.Equal(t, "本文を修正", post["content"])
+
+	// null を明示した項目はエラーになり、省略した項目は検証されない
+	response = doQueryAs(t, r, admin, `mutation { updatePost(id: "2", input: {title: null}) { title } }`)
+	assert.Equal(t, []string{"title"}, errorFields(t, response))
+
+	// 何も指定しない場合は変更しない
+	response = doQueryAs(t, r, admin, `mutation { updatePost(id: "2", input: {}) { title content } }`)
+	post = response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+	assert.Equal(t, "誤字を修正", post["title"])
+	assert.Equal(t, "本文を修正", post["content"])
+}
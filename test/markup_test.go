@@ -0,0 +1,99 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Markdownの本文で投稿を作成し、変換後のHTMLを返す
+func markdownHTML(t *testing.T, r *gin.Engine, content string) string {
+	t.Helper()
+
+	response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: "md", content: %q, contentFormat: MARKDOWN}) { contentHtml } }`, content))
+	require.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["contentHtml"].(string)
+}
+
+// Markdownはスクリプト・イベントハンドラ・危険なURLを取り除いて変換する
+func TestMarkdown(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		content := "**太字** と [リンク](https://example.com)\n\n<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>\n\n[危険](javascript:alert(1))"
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: "md", content: %q, contentFormat: MARKDOWN}) { contentFormat contentHtml } }`, content))
+		require.Nil(t, response["errors"])
+		post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+		assert.Equal(t, "MARKDOWN", post["contentFormat"])
+		html := post["contentHtml"].(string)
+		assert.Contains(t, html, "<strong>太字</strong>")
+		assert.Contains(t, html, `<a href="https://example.com" rel="nofollow ugc">リンク</a>`)
+		assert.NotContains(t, html, "<script")
+		assert.NotContains(t, html, "onerror")
+		assert.NotContains(t, html, "javascript:")
+	})
+}
+
+// 書式を省略した場合と既存の投稿はプレーンテキストとしてエスケープする
+func TestPlainText(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		response := doQuery(t, r, `mutation { createPost(input: {title: "plain", content: "<b>太字</b>\n**そのまま**"}) { contentFormat contentHtml } }`)
+		post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+		assert.Equal(t, "PLAIN", post["contentFormat"])
+		assert.Equal(t, "<p>&lt;b&gt;太字&lt;/b&gt;<br>\n**そのまま**</p>\n", post["contentHtml"])
+
+		response = doQuery(t, r, `query { getPost(id: "1") { contentFormat contentHtml } }`)
+		post = response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+		assert.Equal(t, "PLAIN", post["contentFormat"])
+		assert.Equal(t, "<p>サンプル投稿1</p>\n", post["contentHtml"])
+	})
+}
+
+// 更新すると新しい版の本文で変換し直す
+func TestMarkupAfterUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+
+		response := doQuery(t, r, `mutation { createPost(input: {title: "md", content: "**太字**", contentFormat: MARKDOWN}) { id } }`)
+		id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		query := fmt.Sprintf(`query { getPost(id: %q) { contentHtml } }`, id)
+		doQuery(t, r, query)
+		response = doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updatePost(id: %q, input: {content: "# 見出し"}) { id } }`, id))
+		require.Nil(t, response["errors"])
+		response = doQuery(t, r, query)
+		assert.Equal(t, "<h1>見出し</h1>\n", response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["contentHtml"])
+	})
+}
+
+// 画像は添付ファイルだけを表示し、外部の画像は読み込ませない
+func TestMarkupImages(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	html := markdownHTML(t, r, "![添付](/v1/files/1)")
+	assert.Equal(t, `<p><img src="/v1/files/1" alt="添付"></p>`+"\n", html)
+
+	for _, src := range []string{"https://tracker.example.com/pixel.gif", "//tracker.example.com/pixel.gif", "/v1/files/1/../../admin"} {
+		html = markdownHTML(t, r, "![追跡]("+src+")")
+		assert.NotContains(t, html, "src=", src)
+	}
+}
+
+// リンクのテキスト中のアンカーはリンクにしない
+func TestMarkupAnchorInLink(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	html := markdownHTML(t, r, "[>>4](https://example.com) と >>5")
+	assert.Equal(t, `<p><a href="https://example.com" rel="nofollow ugc">&gt;&gt;4</a> と <a href="#post-5" class="anchor" rel="nofollow ugc">&gt;&gt;5</a></p>`+"\n", html)
+
+	// リンクにならない角括弧の中ではアンカーのまま
+	html = markdownHTML(t, r, "[>>4]")
+	assert.Equal(t, `<p>[<a href="#post-4" class="anchor" rel="nofollow ugc">&gt;&gt;4</a>]</p>`+"\n", html)
+}
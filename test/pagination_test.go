@@ -0,0 +1,82 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// postsクエリのレスポンスから投稿IDとページ情報を取り出す
+func postsPage(t *testing.T, response map[string]interface{}) ([]string, map[string]interface{}, float64) {
+	t.Helper()
+
+	conn := response["data"].(map[string]interface{})["posts"].(map[string]interface{})
+	ids := []string{}
+	for _, edge := range conn["edges"].([]interface{}) {
+		node := edge.(map[string]interface{})["node"].(map[string]interface{})
+		ids = append(ids, node["id"].(string))
+	}
+	return ids, conn["pageInfo"].(map[string]interface{}), conn["totalCount"].(float64)
+}
+
+const postsFields = `edges { cursor node { id } } pageInfo { hasNextPage hasPreviousPage startCursor endCursor } totalCount`
+
+// 前方向のページネーションのテスト
+func TestPostsForwardPagination(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	response := doQuery(t, r, `query { posts(first: 4) { `+postsFields+` } }`)
+	ids, pageInfo, total := postsPage(t, response)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids)
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+	assert.Equal(t, false, pageInfo["hasPreviousPage"])
+	assert.Equal(t, float64(10), total)
+
+	// ページ間で投稿が削除されても、次のページがずれないことを確認
+	admin := adminToken(t, r)
+	doQueryAs(t, r, admin, `mutation { deletePost(id: "2") }`)
+
+	response = doQuery(t, r, fmt.Sprintf(`query { posts(first: 4, after: %q) { %s } }`, pageInfo["endCursor"], postsFields))
+	ids, pageInfo, total = postsPage(t, response)
+	assert.Equal(t, []string{"5", "6", "7", "8"}, ids)
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+	assert.Equal(t, true, pageInfo["hasPreviousPage"])
+	assert.Equal(t, float64(9), total)
+
+	response = doQuery(t, r, fmt.Sprintf(`query { posts(first: 4, after: %q) { %s } }`, pageInfo["endCursor"], postsFields))
+	ids, pageInfo, _ = postsPage(t, response)
+	assert.Equal(t, []string{"9", "10"}, ids)
+	assert.Equal(t, false, pageInfo["hasNextPage"])
+}
+
+// 後方向のページネーションのテスト
+func TestPostsBackwardPagination(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	response := doQuery(t, r, `query { posts(last: 3) { `+postsFields+` } }`)
+	ids, pageInfo, _ := postsPage(t, response)
+	assert.Equal(t, []string{"8", "9", "10"}, ids)
+	assert.Equal(t, true, pageInfo["hasPreviousPage"])
+	assert.Equal(t, false, pageInfo["hasNextPage"])
+
+	response = doQuery(t, r, fmt.Sprintf(`query { posts(last: 10, before: %q) { %s } }`, pageInfo["startCursor"], postsFields))
+	ids, pageInfo, _ = postsPage(t, response)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7"}, ids)
+	assert.Equal(t, false, pageInfo["hasPreviousPage"])
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+}
+
+// 不正な引数のテスト
+func TestPostsInvalidArguments(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	response := doQuery(t, r, `query { posts(first: 2, after: "invalid") { totalCount } }`)
+	assert.NotEmpty(t, response["errors"])
+
+	response = doQuery(t, r, `query { posts(first: 2, last: 2) { totalCount } }`)
+	assert.NotEmpty(t, response["errors"])
+
+	response = doQuery(t, r, `query { posts(first: -1) { totalCount } }`)
+	assert.NotEmpty(t, response["errors"])
+}
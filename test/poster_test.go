@@ -0,0 +1,115 @@
+package resolver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 接続元のIPアドレスを指定して投稿を作成し、作成された投稿を返す
+func createPostFrom(t *testing.T, r *gin.Engine, ip string, input string) map[string]interface{} {
+	t.Helper()
+	return createPostVia(t, r, ip, "", input)
+}
+
+// 接続元のIPアドレスと X-Forwarded-For ヘッダーを指定して投稿を作成し、作成された投稿を返す
+func createPostVia(t *testing.T, r *gin.Engine, ip string, forwardedFor string, input string) map[string]interface{} {
+	t.Helper()
+
+	query := `mutation { createPost(input: {` + input + `}) { name tripcode posterId } }`
+	jsonValue, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, _ := http.NewRequest("POST", "/v1/gql/query", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":50000"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Nil(t, response["errors"])
+	return response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+}
+
+// 名前欄とトリップのテスト
+func TestTripcodes(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	// 名前を省略すると名無しさんになる
+	post := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c"`)
+	assert.Equal(t, "名無しさん", post["name"])
+	assert.Nil(t, post["tripcode"])
+
+	// 同じ秘密の文字列からは同じトリップが得られる
+	first := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c", name: "太郎#himitsu"`)
+	assert.Equal(t, "太郎", first["name"])
+	assert.Regexp(t, `^◆[A-Za-z0-9_-]{10}$`, first["tripcode"])
+	second := createPostFrom(t, r, "192.0.2.2", `title: "t", content: "c", name: "#himitsu"`)
+	assert.Equal(t, "名無しさん", second["name"])
+	assert.Equal(t, first["tripcode"], second["tripcode"])
+
+	// 秘密の文字列が異なればトリップも異なる
+	other := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c", name: "太郎#another"`)
+	assert.NotEqual(t, first["tripcode"], other["tripcode"])
+
+	// 表示名でトリップを偽装できない
+	fake := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c", name: "太郎◆abcdefghij"`)
+	assert.Equal(t, "太郎◇abcdefghij", fake["name"])
+	assert.Nil(t, fake["tripcode"])
+
+	// 名前欄も入力検証の対象になる
+	response := doQuery(t, r, `mutation { createPost(input: {title: "", content: "c", name: "改行\nを含む"}) { id } }`)
+	assert.Equal(t, []string{"title", "name"}, errorFields(t, response))
+}
+
+// 日替わりIDのテスト
+func TestPosterIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	r := routers.SetupRouter(routers.WithClock(func() time.Time { return now }))
+
+	// 同じ日の同じ接続元は同じIDになる
+	morning := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c"`)
+	assert.Regexp(t, `^[A-Za-z0-9_-]{8}$`, morning["posterId"])
+	now = now.Add(time.Hour)
+	evening := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c"`)
+	assert.Equal(t, morning["posterId"], evening["posterId"])
+
+	// 接続元が異なれば別のIDになる
+	other := createPostFrom(t, r, "198.51.100.7", `title: "t", content: "c"`)
+	assert.NotEqual(t, morning["posterId"], other["posterId"])
+
+	// 日本時間の日付が変わると別のIDになる
+	now = time.Date(2024, 10, 1, 15, 0, 0, 0, time.UTC)
+	nextDay := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c"`)
+	assert.NotEqual(t, morning["posterId"], nextDay["posterId"])
+}
+
+// X-Forwarded-For は信頼するプロキシからの場合だけ使う
+func TestPosterIDsForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig()
+	cfg.TrustedProxies = []string{"198.51.100.1"}
+	r := routers.SetupRouter(routers.WithConfig(cfg))
+
+	// 信頼しない接続元からの X-Forwarded-For で他人のIDを名乗れない
+	victim := createPostFrom(t, r, "192.0.2.1", `title: "t", content: "c"`)
+	spoofed := createPostVia(t, r, "192.0.2.2", "192.0.2.1", `title: "t", content: "c"`)
+	assert.NotEqual(t, victim["posterId"], spoofed["posterId"])
+
+	// 信頼するプロキシ経由の場合は転送元のアドレスでIDを計算する
+	proxied := createPostVia(t, r, "198.51.100.1", "192.0.2.1", `title: "t", content: "c"`)
+	assert.Equal(t, victim["posterId"], proxied["posterId"])
+}
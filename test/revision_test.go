@@ -0,0 +1,97 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taro が投稿を作成して本文を修正し、管理者がタイトルを修正して3つの版を作る
+// 投稿IDと管理者のアクセストークンを返す
+func createRevisedPost(t *testing.T, r *gin.Engine) (string, string) {
+	t.Helper()
+
+	admin := adminToken(t, r)
+	taro := registerToken(t, r, "taro")
+	response := doQueryAs(t, r, taro, `mutation { createPost(input: {title: "初版", content: "1行目\n2行目\n3行目"}) { id } }`)
+	id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+	response = doQueryAs(t, r, taro, fmt.Sprintf(`mutation { updatePost(id: %q, input: {content: "1行目\n2行目を修正\n3行目"}) { version } }`, id))
+	require.Nil(t, response["errors"])
+	response = doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updatePost(id: %q, input: {title: "改訂版"}) { version } }`, id))
+	require.Nil(t, response["errors"])
+	return id, admin
+}
+
+// 作成・更新のたびに版が記録され、版を指定して取得できる
+func TestRevisions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		id, _ := createRevisedPost(t, r)
+
+		response := doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { revisions { totalCount edges { node { number title editor { username } } } } } }`, id))
+		revisions := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["revisions"].(map[string]interface{})
+		assert.Equal(t, float64(3), revisions["totalCount"])
+		var numbers, titles, editors []interface{}
+		for _, edge := range revisions["edges"].([]interface{}) {
+			node := edge.(map[string]interface{})["node"].(map[string]interface{})
+			numbers = append(numbers, node["number"])
+			titles = append(titles, node["title"])
+			editors = append(editors, node["editor"].(map[string]interface{})["username"])
+		}
+		assert.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, numbers)
+		assert.Equal(t, []interface{}{"初版", "初版", "改訂版"}, titles)
+		assert.Equal(t, []interface{}{"taro", "taro", "admin"}, editors)
+
+		response = doQuery(t, r, fmt.Sprintf(`query { postRevision(postId: %q, number: 1) { title content } }`, id))
+		revision := response["data"].(map[string]interface{})["postRevision"].(map[string]interface{})
+		assert.Equal(t, "1行目\n2行目\n3行目", revision["content"])
+
+		// 版のページネーション
+		response = doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { revisions(first: 2) { pageInfo { hasNextPage endCursor } } } }`, id))
+		pageInfo := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["revisions"].(map[string]interface{})["pageInfo"].(map[string]interface{})
+		assert.Equal(t, true, pageInfo["hasNextPage"])
+		response = doQuery(t, r, fmt.Sprintf(`query { getPost(id: %q) { revisions(after: %q) { edges { node { number } } } } }`, id, pageInfo["endCursor"]))
+		assert.Len(t, response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["revisions"].(map[string]interface{})["edges"], 1)
+	})
+}
+
+// 版の間の行単位の差分(変更のない項目は空)
+func TestRevisionDiff(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		id, _ := createRevisedPost(t, r)
+
+		response := doQuery(t, r, fmt.Sprintf(`query { revisionDiff(postId: %q, from: 1, to: 2) { from { number } to { number } title content } }`, id))
+		require.Nil(t, response["errors"])
+		diff := response["data"].(map[string]interface{})["revisionDiff"].(map[string]interface{})
+		assert.Equal(t, float64(2), diff["to"].(map[string]interface{})["number"])
+		assert.Equal(t, "", diff["title"])
+		assert.Equal(t, "--- revision 1\n+++ revision 2\n@@ -1,3 +1,3 @@\n 1行目\n-2行目\n+2行目を修正\n 3行目\n", diff["content"])
+		response = doQuery(t, r, fmt.Sprintf(`query { revisionDiff(postId: %q, from: 2, to: 3) { title content } }`, id))
+		diff = response["data"].(map[string]interface{})["revisionDiff"].(map[string]interface{})
+		assert.Equal(t, "--- revision 2\n+++ revision 3\n@@ -1 +1 @@\n-初版\n+改訂版\n", diff["title"])
+		assert.Equal(t, "", diff["content"])
+	})
+}
+
+// 存在しない版と、削除済みの投稿の版は NOT_FOUND
+func TestRevisionNotFound(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		id, admin := createRevisedPost(t, r)
+
+		response := doQuery(t, r, `query { postRevision(postId: "1", number: 2) { number } }`)
+		message, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "revision not found", message)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+		doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: %q) }`, id))
+		response = doQuery(t, r, fmt.Sprintf(`query { revisionDiff(postId: %q, from: 1, to: 2) { title } }`, id))
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
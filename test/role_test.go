@@ -0,0 +1,100 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 投稿の更新・削除は投稿者本人のみ
+func TestPostOwnership(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		taro := registerToken(t, r, "taro")
+		jiro := registerToken(t, r, "jiro")
+		response := doQueryAs(t, r, taro, `mutation { createPost(input: {title: "taroの投稿", content: "本文"}) { id } }`)
+		id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		update := fmt.Sprintf(`mutation { updatePost(id: %q, input: {title: "編集"}) { title } }`, id)
+		remove := fmt.Sprintf(`mutation { deletePost(id: %q) }`, id)
+
+		// 未ログインでは更新できない
+		response = doQuery(t, r, update)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+
+		// 投稿者以外の一般ユーザーは更新・削除できない
+		response = doQueryAs(t, r, jiro, update)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		assert.Equal(t, float64(403), ext["httpStatus"])
+		response = doQueryAs(t, r, jiro, remove)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+
+		response = doQueryAs(t, r, taro, update)
+		assert.Nil(t, response["errors"])
+	})
+}
+
+// 権限の変更は管理者のみで、モデレーターは他人の投稿を削除できる
+func TestUpdateUserRole(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		taro := registerToken(t, r, "taro")
+		jiro := registerToken(t, r, "jiro")
+		response := doQueryAs(t, r, taro, `mutation { createPost(input: {title: "taroの投稿", content: "本文"}) { id } }`)
+		id := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+		remove := fmt.Sprintf(`mutation { deletePost(id: %q) }`, id)
+
+		response = doQueryAs(t, r, admin, `query { viewer { role } }`)
+		assert.Equal(t, "ADMIN", response["data"].(map[string]interface{})["viewer"].(map[string]interface{})["role"])
+
+		response = doQueryAs(t, r, jiro, `query { viewer { id } }`)
+		jiroID := response["data"].(map[string]interface{})["viewer"].(map[string]interface{})["id"].(string)
+		promote := fmt.Sprintf(`mutation { updateUserRole(id: %q, role: MODERATOR) { role } }`, jiroID)
+		response = doQueryAs(t, r, jiro, promote)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+
+		response = doQueryAs(t, r, admin, promote)
+		assert.Equal(t, "MODERATOR", response["data"].(map[string]interface{})["updateUserRole"].(map[string]interface{})["role"])
+		response = doQueryAs(t, r, jiro, remove)
+		assert.True(t, response["data"].(map[string]interface{})["deletePost"].(bool))
+
+		// 削除済みの投稿は NOT_FOUND になる
+		response = doQueryAs(t, r, taro, remove)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
+
+// 管理者は起動時に作成し、登録では管理者のユーザー名を使えない
+func TestAdminProvisioning(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		response := doQuery(t, r, `mutation { register(username: "ADMIN", password: "correct-horse") { token } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "CONFLICT", ext["code"])
+		taro := registerToken(t, r, "taro")
+		response = doQueryAs(t, r, taro, `query { viewer { role } }`)
+		assert.Equal(t, "MEMBER", response["data"].(map[string]interface{})["viewer"].(map[string]interface{})["role"])
+
+		// 再起動しても作成済みの管理者はそのまま使える
+		r = storeRouter(store)
+		response = doQueryAs(t, r, adminToken(t, r), `query { viewer { role } }`)
+		assert.Equal(t, "ADMIN", response["data"].(map[string]interface{})["viewer"].(map[string]interface{})["role"])
+
+		// 管理者でない既存のユーザーには権限を付与せずに起動を中止する
+		cfg := testConfig()
+		cfg.AdminUsername = "taro"
+		assert.Panics(t, func() { routers.SetupRouter(routers.WithConfig(cfg), routers.WithStore(store)) })
+		response = doQuery(t, r, `mutation { login(username: "taro", password: "correct-horse") { user { role } } }`)
+		assert.Equal(t, "MEMBER", response["data"].(map[string]interface{})["login"].(map[string]interface{})["user"].(map[string]interface{})["role"])
+	})
+}
@@ -0,0 +1,149 @@
+package resolver_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 全文検索の結果のタイトルと総数を取得する
+func searchTitles(t *testing.T, r *gin.Engine, query string) ([]string, float64) {
+	t.Helper()
+
+	response := doQuery(t, r, fmt.Sprintf(`query { searchPosts(query: %q) { totalCount edges { score node { title } } } }`, query))
+	require.Nil(t, response["errors"])
+	result := response["data"].(map[string]interface{})["searchPosts"].(map[string]interface{})
+	titles := []string{}
+	previous := 0.0
+	for i, edge := range result["edges"].([]interface{}) {
+		e := edge.(map[string]interface{})
+		titles = append(titles, e["node"].(map[string]interface{})["title"].(string))
+		// 関連度の高い順に並ぶ
+		score := e["score"].(float64)
+		if i > 0 {
+			assert.LessOrEqual(t, score, previous)
+		}
+		previous = score
+	}
+	return titles, result["totalCount"].(float64)
+}
+
+// 検索用の投稿を作成し、タイトルごとの投稿IDを返す
+func createSearchPosts(t *testing.T, r *gin.Engine) map[string]string {
+	t.Helper()
+
+	ids := map[string]string{}
+	for _, post := range []struct{ title, content string }{
+		{"Go言語の並行処理", "goroutineとチャネルの使い方"},
+		{"今日の天気", "東京は晴れ。Go言語とは関係ない話"},
+		{"ＧＯ入門", "初心者向けのGo言語入門"},
+	} {
+		response := doQuery(t, r, fmt.Sprintf(`mutation { createPost(input: {title: %q, content: %q}) { id } }`, post.title, post.content))
+		require.Nil(t, response["errors"])
+		ids[post.title] = response["data"].(map[string]interface{})["createPost"].(map[string]interface{})["id"].(string)
+	}
+	return ids
+}
+
+// 全文検索の語句・フレーズ・除外のテスト
+func TestSearchPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		createSearchPosts(t, r)
+
+		// 日本語は2-gramで検索できる(サンプルデータの本文 "サンプル投稿N")
+		_, total := searchTitles(t, r, "サンプル")
+		assert.Equal(t, float64(10), total)
+		titles, _ := searchTitles(t, r, "投稿10")
+		assert.Equal(t, []string{"投稿10"}, titles)
+
+		// 本文だけに含む投稿は、タイトルにも含む投稿より下位になる
+		titles, total = searchTitles(t, r, "Go言語")
+		assert.Equal(t, float64(3), total)
+		assert.Equal(t, "今日の天気", titles[2])
+
+		// 除外とフレーズ
+		titles, _ = searchTitles(t, r, "Go言語 -天気")
+		assert.ElementsMatch(t, []string{"Go言語の並行処理", "ＧＯ入門"}, titles)
+		titles, _ = searchTitles(t, r, `"言語の並行"`)
+		assert.Equal(t, []string{"Go言語の並行処理"}, titles)
+		titles, _ = searchTitles(t, r, `"並行の言語"`)
+		assert.Empty(t, titles)
+
+		// 全角・半角と大文字・小文字を区別しない、1文字の検索語
+		titles, _ = searchTitles(t, r, "ｇｏ入門")
+		assert.Equal(t, []string{"ＧＯ入門"}, titles)
+		titles, _ = searchTitles(t, r, "晴")
+		assert.Equal(t, []string{"今日の天気"}, titles)
+	})
+}
+
+// 投稿の更新・削除はすぐに検索結果に反映される
+func TestSearchIndexUpdates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		ids := createSearchPosts(t, r)
+
+		response := doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updatePost(id: %q, input: {title: "Rustの話", content: "所有権について"}) { id } }`, ids["今日の天気"]))
+		require.Nil(t, response["errors"])
+		titles, _ := searchTitles(t, r, "天気")
+		assert.Empty(t, titles)
+		titles, _ = searchTitles(t, r, "rust 所有権")
+		assert.Equal(t, []string{"Rustの話"}, titles)
+		response = doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: %q) }`, ids["ＧＯ入門"]))
+		require.Nil(t, response["errors"])
+		titles, _ = searchTitles(t, r, "入門")
+		assert.Empty(t, titles)
+	})
+}
+
+// 全文検索の結果のページネーションのテスト
+func TestSearchPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		response := doQuery(t, r, `query { searchPosts(query: "サンプル", first: 4) { pageInfo { hasNextPage endCursor } edges { node { id } } } }`)
+		page := response["data"].(map[string]interface{})["searchPosts"].(map[string]interface{})
+		assert.Len(t, page["edges"], 4)
+		pageInfo := page["pageInfo"].(map[string]interface{})
+		assert.Equal(t, true, pageInfo["hasNextPage"])
+		response = doQuery(t, r, fmt.Sprintf(`query { searchPosts(query: "サンプル", first: 10, after: %q) { pageInfo { hasNextPage hasPreviousPage } edges { node { id } } } }`, pageInfo["endCursor"]))
+		page = response["data"].(map[string]interface{})["searchPosts"].(map[string]interface{})
+		assert.Len(t, page["edges"], 6)
+		assert.Equal(t, false, page["pageInfo"].(map[string]interface{})["hasNextPage"])
+		assert.Equal(t, true, page["pageInfo"].(map[string]interface{})["hasPreviousPage"])
+	})
+}
+
+// 検索語が含まれない検索文字列はエラー
+func TestSearchInvalidQuery(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	for _, query := range []string{"", "-天気", `"!?"`} {
+		response := doQuery(t, r, fmt.Sprintf(`query { searchPosts(query: %q) { totalCount } }`, query))
+		assert.Equal(t, []string{"query"}, errorFields(t, response), query)
+	}
+}
+
+// データベースを開き直しても保存済みの投稿から検索用のインデックスが作成される
+func TestSQLiteSearchIndexRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bbs.db")
+	r, _ := setupSQLiteRouter(t, path)
+	admin := adminToken(t, r)
+	ids := createSearchPosts(t, r)
+	doQueryAs(t, r, admin, fmt.Sprintf(`mutation { updatePost(id: %q, input: {title: "Rustの話", content: "所有権について"}) { id } }`, ids["今日の天気"]))
+	doQueryAs(t, r, admin, fmt.Sprintf(`mutation { deletePost(id: %q) }`, ids["ＧＯ入門"]))
+
+	r, _ = setupSQLiteRouter(t, path)
+	titles, _ := searchTitles(t, r, "rust")
+	assert.Equal(t, []string{"Rustの話"}, titles)
+	titles, _ = searchTitles(t, r, "入門")
+	assert.Empty(t, titles)
+}
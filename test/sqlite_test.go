@@ -0,0 +1,125 @@
+package resolver_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SQLiteストアを使ってルーターを初期化する
+func setupSQLiteRouter(t *testing.T, path string) (*gin.Engine, *models.SQLiteStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store, err := models.OpenSQLiteStore(context.Background(), path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	return routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(store)), store
+}
+
+// テスト対象のストア(どちらもサンプルデータを持つ)
+var testStores = []struct {
+	name string
+	open func(t *testing.T) models.Store
+}{
+	{"memory", func(t *testing.T) models.Store {
+		return models.NewMemoryStore(models.SeedPosts)
+	}},
+	{"sqlite", func(t *testing.T) models.Store {
+		store, err := models.OpenSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "bbs.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	}},
+}
+
+// インメモリストアとSQLiteストアのそれぞれで、新しいストアを渡してテストを実行する
+func forEachStore(t *testing.T, test func(t *testing.T, store models.Store)) {
+	for _, s := range testStores {
+		t.Run(s.name, func(t *testing.T) {
+			test(t, s.open(t))
+		})
+	}
+}
+
+// ストアを指定してテスト用の設定でルーターを初期化する
+func storeRouter(store models.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return routers.SetupRouter(routers.WithConfig(testConfig()), routers.WithStore(store))
+}
+
+// SQLiteストアでの作成・取得・更新・削除のテスト
+func TestSQLitePostCRUD(t *testing.T) {
+	r, _ := setupSQLiteRouter(t, filepath.Join(t.TempDir(), "bbs.db"))
+	admin := adminToken(t, r)
+
+	// サンプルデータが投入されていることを確認
+	response := doQuery(t, r, `query { getPost(id: "5") { id title content } }`)
+	post := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+	assert.Equal(t, "投稿5", post["title"])
+
+	// 作成
+	response = doQuery(t, r, `mutation { createPost(input: {title: "SQLite", content: "保存される投稿", name: "太郎#himitsu"}) { id tripcode } }`)
+	created := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	assert.Equal(t, "11", created["id"])
+
+	// 名前欄とトリップが保存されている
+	response = doQuery(t, r, `query { getPost(id: "11") { name tripcode } }`)
+	post = response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+	assert.Equal(t, "太郎", post["name"])
+	assert.Equal(t, created["tripcode"], post["tripcode"])
+
+	// 更新
+	response = doQueryAs(t, r, admin, `mutation { updatePost(id: "11", input: {title: "更新", content: "更新後"}) { title content createdAt updatedAt } }`)
+	updated := response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+	assert.Equal(t, "更新", updated["title"])
+	assert.NotEmpty(t, updated["createdAt"])
+	assert.NotEmpty(t, updated["updatedAt"])
+	assert.Equal(t, "更新後", updated["content"])
+
+	// 一覧
+	response = doQuery(t, r, `query { getAllPosts(page: 2, per_page: 10) { id } }`)
+	posts := response["data"].(map[string]interface{})["getAllPosts"].([]interface{})
+	assert.Len(t, posts, 1)
+
+	// カーソルページネーション
+	response = doQuery(t, r, `query { posts(last: 2) { `+postsFields+` } }`)
+	ids, pageInfo, total := postsPage(t, response)
+	assert.Equal(t, []string{"10", "11"}, ids)
+	assert.Equal(t, true, pageInfo["hasPreviousPage"])
+	assert.Equal(t, float64(11), total)
+
+	// 削除
+	response = doQueryAs(t, r, admin, `mutation { deletePost(id: "11") }`)
+	assert.True(t, response["data"].(map[string]interface{})["deletePost"].(bool))
+
+	// 削除後は見つからない
+	response = doQuery(t, r, `query { getPost(id: "11") { id } }`)
+	assert.NotEmpty(t, response["errors"])
+}
+
+// 再起動後もデータが保持され、サンプルデータが再投入されないことのテスト
+func TestSQLitePersistsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bbs.db")
+
+	r, store := setupSQLiteRouter(t, path)
+	admin := adminToken(t, r)
+	doQueryAs(t, r, admin, `mutation { deletePost(id: "1") }`)
+	doQuery(t, r, `mutation { createPost(input: {title: "残る投稿", content: "再起動後も残る"}) { id } }`)
+	require.NoError(t, store.Close())
+
+	r, _ = setupSQLiteRouter(t, path)
+	response := doQuery(t, r, `query { getAllPosts(page: 1, per_page: 100) { id title } }`)
+	posts := response["data"].(map[string]interface{})["getAllPosts"].([]interface{})
+	assert.Len(t, posts, 10)
+	assert.Equal(t, "2", posts[0].(map[string]interface{})["id"])
+	assert.Equal(t, "残る投稿", posts[9].(map[string]interface{})["title"])
+}
@@ -0,0 +1,171 @@
+package resolver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graphql-ws プロトコルのメッセージ
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WebSocketで接続し、connection_init を送信する
+// token が空の場合は payload を付けずに送信する
+func connect(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/gql/query"
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	init := wsMessage{Type: "connection_init"}
+	if token != "" {
+		init.Payload, _ = json.Marshal(map[string]string{"Authorization": "Bearer " + token})
+	}
+	require.NoError(t, conn.WriteJSON(init))
+	return conn
+}
+
+// WebSocketで接続し、サブスクリプションを開始する
+func subscribe(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+
+	conn := connect(t, server, "")
+	ack := readMessage(t, conn)
+	require.Equal(t, "connection_ack", ack.Type)
+
+	payload, _ := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload}))
+	return conn
+}
+
+// 次のメッセージを読み込む(ping は読み飛ばす)
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var msg wsMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		if msg.Type != "ping" && msg.Type != "ka" {
+			return msg
+		}
+	}
+}
+
+// HTTPでミューテーションを送信する
+// token が空の場合は未ログインとして送信する
+func postMutation(t *testing.T, server *httptest.Server, token string, query string) {
+	t.Helper()
+
+	jsonValue, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, err := http.NewRequest("POST", server.URL+"/v1/gql/query", bytes.NewBuffer(jsonValue))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// サブスクリプションの通知データを取り出す
+func nextData(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+
+	msg := readMessage(t, conn)
+	require.Equal(t, "next", msg.Type, string(msg.Payload))
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+	return payload.Data
+}
+
+// 投稿イベントのサブスクリプションのテスト
+func TestPostSubscriptions(t *testing.T) {
+	r, _ := setupTestRouter()
+	admin := adminToken(t, r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	created := subscribe(t, server, `subscription { postCreated { id title } }`)
+	updated := subscribe(t, server, `subscription { postUpdated(id: "3") { id title } }`)
+	deleted := subscribe(t, server, `subscription { postDeleted }`)
+	// サブスクリプションの登録を待つ
+	time.Sleep(100 * time.Millisecond)
+
+	postMutation(t, server, "", `mutation { createPost(input: {title: "新しいスレッド", content: "本文"}) { id } }`)
+	post := nextData(t, created)["postCreated"].(map[string]interface{})
+	assert.Equal(t, "11", post["id"])
+	assert.Equal(t, "新しいスレッド", post["title"])
+
+	// 別の投稿の更新は通知されない
+	postMutation(t, server, admin, `mutation { updatePost(id: "2", input: {title: "対象外"}) { id } }`)
+	postMutation(t, server, admin, `mutation { updatePost(id: "3", input: {title: "更新"}) { id } }`)
+	post = nextData(t, updated)["postUpdated"].(map[string]interface{})
+	assert.Equal(t, "3", post["id"])
+	assert.Equal(t, "更新", post["title"])
+
+	postMutation(t, server, admin, `mutation { deletePost(id: "3") }`)
+	assert.Equal(t, "3", nextData(t, deleted)["postDeleted"])
+}
+
+// コメントのサブスクリプションのテスト
+func TestCommentSubscription(t *testing.T) {
+	r, _ := setupTestRouter()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn := subscribe(t, server, `subscription { commentCreated(postId: "5") { postId content } }`)
+	time.Sleep(100 * time.Millisecond)
+
+	postMutation(t, server, "", `mutation { createComment(input: {postId: "4", content: "対象外"}) { id } }`)
+	postMutation(t, server, "", `mutation { createComment(input: {postId: "5", content: "新着コメント"}) { id } }`)
+	comment := nextData(t, conn)["commentCreated"].(map[string]interface{})
+	assert.Equal(t, "5", comment["postId"])
+	assert.Equal(t, "新着コメント", comment["content"])
+}
+
+// connection_init の payload のトークンで認証するテスト
+func TestWebsocketInitAuthentication(t *testing.T) {
+	r, _ := setupTestRouter()
+	token := registerToken(t, r, "wsuser")
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// payload のトークンで認証したユーザーとして操作を実行する
+	conn := connect(t, server, token)
+	require.Equal(t, "connection_ack", readMessage(t, conn).Type)
+	payload, _ := json.Marshal(map[string]string{"query": `query { viewer { username } }`})
+	require.NoError(t, conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload}))
+	viewer := nextData(t, conn)["viewer"].(map[string]interface{})
+	assert.Equal(t, "wsuser", viewer["username"])
+
+	// 不正なトークンの場合は接続を確立しない
+	conn = connect(t, server, "invalid")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			break
+		}
+		assert.NotEqual(t, "connection_ack", msg.Type)
+	}
+}
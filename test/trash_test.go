@@ -0,0 +1,118 @@
+package resolver_test
+
+import (
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ゴミ箱の投稿一覧を取得する
+func trashedPosts(t *testing.T, r *gin.Engine, token string) ([]interface{}, float64) {
+	t.Helper()
+
+	response := doQueryAs(t, r, token, `query { trashedPosts { totalCount edges { node { id deletedAt deletedBy { username } } } } }`)
+	require.Nil(t, response["errors"])
+	trash := response["data"].(map[string]interface{})["trashedPosts"].(map[string]interface{})
+	return trash["edges"].([]interface{}), trash["totalCount"].(float64)
+}
+
+// 削除した投稿は一覧・詳細・検索に表示されず、返信もできない
+func TestSoftDeletePost(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+
+		response := doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+		require.Nil(t, response["errors"])
+		response = doQuery(t, r, `query { getPost(id: "3") { id } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+		response = doQuery(t, r, `query { getAllPosts(page: 1, per_page: 100) { id } }`)
+		assert.Len(t, response["data"].(map[string]interface{})["getAllPosts"], 9)
+		titles, _ := searchTitles(t, r, "投稿3")
+		assert.Empty(t, titles)
+		response = doQuery(t, r, `mutation { createComment(input: {postId: "3", content: "x"}) { id } }`)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+		response = doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
+
+// ゴミ箱は管理者のみ閲覧できる
+func TestTrashedPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		taro := registerToken(t, r, "taro")
+		doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+
+		edges, total := trashedPosts(t, r, admin)
+		assert.Equal(t, float64(1), total)
+		node := edges[0].(map[string]interface{})["node"].(map[string]interface{})
+		assert.Equal(t, "3", node["id"])
+		assert.NotNil(t, node["deletedAt"])
+		assert.Equal(t, "admin", node["deletedBy"].(map[string]interface{})["username"])
+		response := doQueryAs(t, r, taro, `query { trashedPosts { totalCount } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+	})
+}
+
+// 復元すると投稿がコメントとともに元に戻る
+func TestRestorePost(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		taro := registerToken(t, r, "taro")
+		createComment(t, r, "3", "", "消える前のコメント")
+		doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+
+		response := doQueryAs(t, r, taro, `mutation { restorePost(id: "3") { id } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		response = doQueryAs(t, r, admin, `mutation { restorePost(id: "3") { id deletedAt comments { totalCount } } }`)
+		require.Nil(t, response["errors"])
+		restored := response["data"].(map[string]interface{})["restorePost"].(map[string]interface{})
+		assert.Nil(t, restored["deletedAt"])
+		assert.Equal(t, float64(1), restored["comments"].(map[string]interface{})["totalCount"])
+		titles, _ := searchTitles(t, r, "投稿3")
+		assert.Equal(t, []string{"投稿3"}, titles)
+		_, total := trashedPosts(t, r, admin)
+		assert.Equal(t, float64(0), total)
+
+		// 削除されていない投稿は復元できない
+		response = doQueryAs(t, r, admin, `mutation { restorePost(id: "3") { id } }`)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
+
+// 指定した日時より前に削除された投稿だけを完全に削除する
+func TestPurgeDeletedPosts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		taro := registerToken(t, r, "taro")
+		doQueryAs(t, r, admin, `mutation { deletePost(id: "3") }`)
+		doQueryAs(t, r, admin, `mutation { deletePost(id: "4") }`)
+
+		response := doQueryAs(t, r, admin, `mutation { purgeDeletedPosts(olderThan: "2000-01-01T00:00:00Z") }`)
+		assert.Equal(t, float64(0), response["data"].(map[string]interface{})["purgeDeletedPosts"])
+		response = doQueryAs(t, r, taro, `mutation { purgeDeletedPosts(olderThan: "2100-01-01T00:00:00Z") }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "FORBIDDEN", ext["code"])
+		response = doQueryAs(t, r, admin, `mutation { purgeDeletedPosts(olderThan: "2100-01-01T00:00:00Z") }`)
+		assert.Equal(t, float64(2), response["data"].(map[string]interface{})["purgeDeletedPosts"])
+		_, total := trashedPosts(t, r, admin)
+		assert.Equal(t, float64(0), total)
+		response = doQueryAs(t, r, admin, `mutation { restorePost(id: "3") { id } }`)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "NOT_FOUND", ext["code"])
+	})
+}
@@ -0,0 +1,74 @@
+package resolver_test
+
+import (
+	"testing"
+
+	"bbs-gql-project/config"
+	"bbs-gql-project/models"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// アカウント登録と、ユーザー名の重複・入力値の検証
+func TestRegister(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+
+		response := doQuery(t, r, `mutation { register(username: "taro", password: "correct-horse") { token expiresAt user { id username } } }`)
+		assert.Nil(t, response["errors"])
+		payload := response["data"].(map[string]interface{})["register"].(map[string]interface{})
+		assert.NotEmpty(t, payload["token"])
+		assert.NotEmpty(t, payload["expiresAt"])
+		assert.Equal(t, "taro", payload["user"].(map[string]interface{})["username"])
+
+		// ユーザー名は大文字・小文字を区別せずに重複を判定する
+		response = doQuery(t, r, `mutation { register(username: "TARO", password: "another-pass") { token } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "CONFLICT", ext["code"])
+
+		response = doQuery(t, r, `mutation { register(username: "x!", password: "short") { token } }`)
+		assert.Equal(t, []string{"username", "password"}, errorFields(t, response))
+	})
+}
+
+// ログインと、パスワード誤り・存在しないユーザーのエラー
+func TestLogin(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		registerToken(t, r, "taro")
+
+		response := doQuery(t, r, `mutation { login(username: "taro", password: "correct-horse") { token user { username } } }`)
+		assert.Nil(t, response["errors"])
+		assert.NotEmpty(t, response["data"].(map[string]interface{})["login"].(map[string]interface{})["token"])
+
+		// パスワード誤りと存在しないユーザーは同じエラーになる
+		response = doQuery(t, r, `mutation { login(username: "taro", password: "wrong-password") { token } }`)
+		message, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+		response = doQuery(t, r, `mutation { login(username: "jiro", password: "wrong-password") { token } }`)
+		message2, _ := firstErrorExtensions(t, response)
+		assert.Equal(t, message, message2)
+	})
+}
+
+// 匿名投稿のテスト
+func TestAnonymousPosting(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	// 匿名投稿の投稿者は null になる
+	response := doQuery(t, r, `mutation { createPost(input: {title: "匿名", content: "名無しさん"}) { author { id } } }`)
+	post := response["data"].(map[string]interface{})["createPost"].(map[string]interface{})
+	assert.Nil(t, post["author"])
+
+	// 匿名投稿を無効にした場合はログインが必要になる
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.AllowAnonymous = false
+	r = routers.SetupRouter(routers.WithConfig(cfg))
+
+	response = doQuery(t, r, `mutation { createPost(input: {title: "匿名", content: "名無しさん"}) { id } }`)
+	_, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "UNAUTHENTICATED", ext["code"])
+}
@@ -0,0 +1,64 @@
+package resolver_test
+
+import (
+	"strings"
+	"testing"
+
+	"bbs-gql-project/config"
+	"bbs-gql-project/routers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// extensions.fields から入力項目名の一覧を取り出す
+func errorFields(t *testing.T, response map[string]interface{}) []string {
+	t.Helper()
+
+	_, ext := firstErrorExtensions(t, response)
+	assert.Equal(t, "BAD_REQUEST", ext["code"])
+	fields := []string{}
+	for _, f := range ext["fields"].([]interface{}) {
+		fields = append(fields, f.(map[string]interface{})["field"].(string))
+	}
+	return fields
+}
+
+// すべての入力エラーがまとめて返されることのテスト
+func TestCreatePostValidationAggregatesErrors(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	response := doQuery(t, r, `mutation { createPost(input: {title: "   ", content: "\u0007ベル"}) { id } }`)
+	assert.Nil(t, response["data"])
+	assert.Equal(t, []string{"title", "content"}, errorFields(t, response))
+}
+
+// 更新時も入力値が検証されることのテスト
+func TestUpdatePostValidation(t *testing.T) {
+	r, _ := setupTestRouter()
+	admin := adminToken(t, r)
+
+	response := doQueryAs(t, r, admin, `mutation { updatePost(id: "1", input: {title: "改行\nを含む", content: ""}) { id } }`)
+	assert.Equal(t, []string{"title", "content"}, errorFields(t, response))
+
+	// 投稿は変更されていない
+	response = doQuery(t, r, `query { getPost(id: "1") { title content } }`)
+	post := response["data"].(map[string]interface{})["getPost"].(map[string]interface{})
+	assert.Equal(t, "投稿1", post["title"])
+	assert.Equal(t, "サンプル投稿1", post["content"])
+}
+
+// 上限値を設定で変更できることのテスト
+func TestValidationLimitsAreConfigurable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.MaxTitleLength = 5
+	r := routers.SetupRouter(routers.WithConfig(cfg))
+
+	// 文字数はバイト数ではなく文字数で数える
+	response := doQuery(t, r, `mutation { createPost(input: {title: "あいうえお", content: "本文"}) { title } }`)
+	assert.Nil(t, response["errors"])
+
+	response = doQuery(t, r, `mutation { createPost(input: {title: "あいうえおか", content: "`+strings.Repeat("長", 20)+`"}) { id } }`)
+	assert.Equal(t, []string{"title"}, errorFields(t, response))
+}
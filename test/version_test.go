@@ -0,0 +1,98 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	"bbs-gql-project/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 古いバージョンを元にした更新・削除は CONFLICT になる
+func TestVersionConflict(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		response := doQuery(t, r, `query { getPost(id: "4") { version } }`)
+		assert.Equal(t, float64(1), response["data"].(map[string]interface{})["getPost"].(map[string]interface{})["version"])
+		response = doQueryAs(t, r, admin, `mutation { updatePost(id: "4", input: {title: "モデレーターA"}, expectedVersion: 1) { title version } }`)
+		post := response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+		assert.Equal(t, "モデレーターA", post["title"])
+		assert.Equal(t, float64(2), post["version"])
+
+		response = doQueryAs(t, r, admin, `mutation { updatePost(id: "4", input: {title: "モデレーターB"}, expectedVersion: 1) { title } }`)
+		_, ext := firstErrorExtensions(t, response)
+		assert.Equal(t, "CONFLICT", ext["code"])
+		assert.Equal(t, float64(409), ext["httpStatus"])
+		assert.Equal(t, float64(2), ext["currentVersion"])
+
+		response = doQueryAs(t, r, admin, `mutation { deletePost(id: "4", expectedVersion: 1) }`)
+		_, ext = firstErrorExtensions(t, response)
+		assert.Equal(t, "CONFLICT", ext["code"])
+		assert.Equal(t, float64(2), ext["currentVersion"])
+	})
+}
+
+// expectedVersion を省略した更新は常に成功し、最新のバージョンを指定した削除は成功する
+func TestVersionUpToDate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(store)
+		admin := adminToken(t, r)
+		response := doQueryAs(t, r, admin, `mutation { updatePost(id: "4", input: {title: "モデレーターA"}, expectedVersion: 1) { version } }`)
+		assert.Equal(t, float64(2), response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})["version"])
+
+		response = doQueryAs(t, r, admin, `mutation { updatePost(id: "4", input: {content: "追記"}) { version } }`)
+		assert.Equal(t, float64(3), response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})["version"])
+		response = doQueryAs(t, r, admin, `mutation { deletePost(id: "4", expectedVersion: 3) }`)
+		assert.True(t, response["data"].(map[string]interface{})["deletePost"].(bool))
+	})
+}
+
+// 最初の更新の直前に、別の利用者による更新を割り込ませる投稿の保存先
+type racingPostStore struct {
+	models.PostStore
+	raced *bool
+}
+
+// 保存されている投稿のタイトルを変更してから更新する(割り込みは最初の1回だけ)
+func (s racingPostStore) Update(ctx context.Context, post *models.Post, editorID int) error {
+	if !*s.raced {
+		*s.raced = true
+		other, err := s.PostStore.Get(ctx, post.ID)
+		if err != nil {
+			return err
+		}
+		other.Title = "別の編集"
+		if err := s.PostStore.Update(ctx, other, 0); err != nil {
+			return err
+		}
+	}
+	return s.PostStore.Update(ctx, post, editorID)
+}
+
+// 更新の直前に別の利用者の更新が割り込むストア
+type racingStore struct {
+	models.Store
+	raced *bool
+}
+
+// 更新が割り込む投稿の保存先を返す
+func (s racingStore) Posts() models.PostStore {
+	return racingPostStore{s.Store.Posts(), s.raced}
+}
+
+// expectedVersion を省略した更新は、他の更新と競合しても最新の内容に対して適用される
+func TestUpdateWithoutVersionRetries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store models.Store) {
+		r := storeRouter(racingStore{store, new(bool)})
+		admin := adminToken(t, r)
+
+		response := doQueryAs(t, r, admin, `mutation { updatePost(id: "4", input: {content: "追記"}) { title content version } }`)
+		assert.Nil(t, response["errors"])
+		post := response["data"].(map[string]interface{})["updatePost"].(map[string]interface{})
+		assert.Equal(t, "別の編集", post["title"])
+		assert.Equal(t, "追記", post["content"])
+		assert.Equal(t, float64(3), post["version"])
+	})
+}
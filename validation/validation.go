@@ -0,0 +1,271 @@
+/*
+* 入力検証
+* 入力項目ごとのエラーを集約し、まとめて返す
+ */
+
+package validation
+
+import (
+	"bbs-gql-project/models"
+	"bbs-gql-project/search"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// アカウントの入力値の制約
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcryptが扱える最大バイト数
+)
+
+// 名前欄の最大文字数(トリップの "#秘密の文字列" を含む)
+const maxNameLength = 64
+
+// 掲示板の入力値の制約
+const (
+	maxBoardNameLength        = 64
+	maxBoardDescriptionLength = 500
+)
+
+// 検索文字列・絞り込み条件の文字列の最大文字数
+const maxSearchQueryLength = 200
+
+// 添付ファイル名の最大文字数
+const maxFilenameLength = 255
+
+// 添付ファイルとして許可するMIMEタイプ
+// クライアントが申告したものではなく、内容から判定したものを使用する
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// 掲示板のスラッグに使用できる文字列(英小文字・数字・ハイフン、2〜32文字)
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,31}$`)
+
+// ユーザー名に使用できる文字列(英数字とアンダースコア、3〜32文字)
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// 入力値の上限設定
+type Limits struct {
+	MaxTitleLength    int // タイトルの最大文字数
+	MaxContentLength  int // 本文の最大文字数
+	MaxAttachments    int // 1つの投稿に添付できるファイルの数
+	MaxAttachmentSize int // 添付ファイル1つの最大バイト数
+}
+
+// 本文の最大文字数を制限した設定を返す
+// maxContentLength が0の場合や現在の設定より大きい場合は変更しない
+func (l Limits) WithMaxContentLength(maxContentLength int) Limits {
+	if maxContentLength > 0 && maxContentLength < l.MaxContentLength {
+		l.MaxContentLength = maxContentLength
+	}
+	return l
+}
+
+// 入力項目ごとのエラーを集約する
+type Errors struct {
+	fields []models.FieldError
+}
+
+// エラーを追加する
+func (e *Errors) Add(field string, message string) {
+	e.fields = append(e.fields, models.FieldError{Field: field, Message: message})
+}
+
+// エラーがあれば入力検証エラーを返す
+func (e *Errors) Err() error {
+	if len(e.fields) == 0 {
+		return nil
+	}
+	return models.ValidationError(e.fields)
+}
+
+// 1行のテキスト(タイトルなど)を検証する
+// 改行を含むすべての制御文字を禁止する
+func (e *Errors) Line(field string, value string, maxLength int) {
+	e.text(field, value, maxLength, func(r rune) bool { return false })
+}
+
+// 複数行のテキスト(本文など)を検証する
+// 改行とタブ以外の制御文字を禁止する
+func (e *Errors) Text(field string, value string, maxLength int) {
+	e.text(field, value, maxLength, func(r rune) bool { return r == '\n' || r == '\r' || r == '\t' })
+}
+
+// テキストを検証する
+// allowed が true を返す制御文字は許可する
+func (e *Errors) text(field string, value string, maxLength int, allowed func(rune) bool) {
+	if !utf8.ValidString(value) {
+		e.Add(field, "must be valid UTF-8")
+		return
+	}
+	if strings.TrimSpace(value) == "" {
+		e.Add(field, "is required")
+		return
+	}
+	if n := utf8.RuneCountInString(value); n > maxLength {
+		e.Add(field, fmt.Sprintf("must be at most %d characters (got %d)", maxLength, n))
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && !allowed(r) {
+			e.Add(field, fmt.Sprintf("must not contain control character %U", r))
+			break
+		}
+	}
+}
+
+// 投稿の入力値を検証する
+func (l Limits) Post(title string, content string) error {
+	var errs Errors
+	l.PostFields(&errs, &title, &content)
+	return errs.Err()
+}
+
+// 投稿の入力値のうち、指定された項目だけを検証する(nilの項目は検証しない)
+func (l Limits) PostFields(errs *Errors, title *string, content *string) {
+	if title != nil {
+		errs.Line("title", *title, l.MaxTitleLength)
+	}
+	if content != nil {
+		errs.Text("content", *content, l.MaxContentLength)
+	}
+}
+
+// 添付ファイルの数を検証する
+func (l Limits) AttachmentCount(errs *Errors, count int) bool {
+	if count > l.MaxAttachments {
+		errs.Add("attachments", fmt.Sprintf("must be at most %d files (got %d)", l.MaxAttachments, count))
+		return false
+	}
+	return true
+}
+
+// 添付ファイルの大きさを検証する(内容を読み込む前に、申告された大きさで確認する)
+func (l Limits) AttachmentSize(errs *Errors, field string, size int64) bool {
+	if size > int64(l.MaxAttachmentSize) {
+		errs.Add(field, fmt.Sprintf("must be at most %d bytes (got %d)", l.MaxAttachmentSize, size))
+		return false
+	}
+	return true
+}
+
+// 添付ファイルのファイル名と内容を検証する
+// ファイル名からはディレクトリを取り除き、内容から判定したMIMEタイプとともに返す
+func (l Limits) Attachment(errs *Errors, field string, filename string, data []byte) (string, string) {
+	if !l.AttachmentSize(errs, field, int64(len(data))) {
+		return "", ""
+	}
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	errs.Line(field+".filename", filename, maxFilenameLength)
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		errs.Add(field, fmt.Sprintf("unsupported file type %q", contentType))
+	}
+	return filename, contentType
+}
+
+// 名前欄の入力値を検証する
+func PosterName(errs *Errors, name string) {
+	errs.Line("name", name, maxNameLength)
+}
+
+// コメントの入力値を検証する
+func (l Limits) Comment(content string) error {
+	var errs Errors
+	l.CommentFields(&errs, &content)
+	return errs.Err()
+}
+
+// コメントの入力値のうち、指定された項目だけを検証する(nilの項目は検証しない)
+func (l Limits) CommentFields(errs *Errors, content *string) {
+	if content != nil {
+		errs.Text("content", *content, l.MaxContentLength)
+	}
+}
+
+// 掲示板作成の入力値を検証する
+func Board(slug string, name string, description string, maxThreads int, maxReplies int, maxPostLength int) error {
+	var errs Errors
+	if !slugPattern.MatchString(slug) {
+		errs.Add("slug", "must be 2-32 characters of lowercase letters, digits or hyphens")
+	}
+	errs.Line("name", name, maxBoardNameLength)
+	if description != "" {
+		errs.Text("description", description, maxBoardDescriptionLength)
+	}
+	BoardLimits(&errs, &maxThreads, &maxReplies, &maxPostLength)
+	return errs.Err()
+}
+
+// 掲示板の上限設定のうち、指定された項目だけを検証する(nilの項目は検証しない)
+func BoardLimits(errs *Errors, maxThreads *int, maxReplies *int, maxPostLength *int) {
+	for _, limit := range []struct {
+		field string
+		value *int
+	}{
+		{"maxThreads", maxThreads},
+		{"maxReplies", maxReplies},
+		{"maxPostLength", maxPostLength},
+	} {
+		if limit.value != nil && *limit.value < 0 {
+			errs.Add(limit.field, "must not be negative")
+		}
+	}
+}
+
+// 掲示板名を検証する
+func BoardName(name string) error {
+	var errs Errors
+	errs.Line("name", name, maxBoardNameLength)
+	return errs.Err()
+}
+
+// 検索文字列を検証して解析する
+func SearchQuery(query string) (search.Query, error) {
+	var errs Errors
+	errs.Line("query", query, maxSearchQueryLength)
+	if err := errs.Err(); err != nil {
+		return search.Query{}, err
+	}
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		errs.Add("query", err.Error())
+		return search.Query{}, errs.Err()
+	}
+	return q, nil
+}
+
+// 投稿一覧の絞り込み条件のうち、指定された項目だけを検証する(nilの項目は検証しない)
+func PostFilter(errs *Errors, titleContains *string, contentContains *string, createdAfter *time.Time, createdBefore *time.Time) {
+	if titleContains != nil {
+		errs.Line("filter.titleContains", *titleContains, maxSearchQueryLength)
+	}
+	if contentContains != nil {
+		errs.Line("filter.contentContains", *contentContains, maxSearchQueryLength)
+	}
+	if createdAfter != nil && createdBefore != nil && !createdAfter.Before(*createdBefore) {
+		errs.Add("filter.createdBefore", "must be later than createdAfter")
+	}
+}
+
+// アカウント登録の入力値を検証する
+func Credentials(username string, password string) error {
+	var errs Errors
+	if !usernamePattern.MatchString(username) {
+		errs.Add("username", "must be 3-32 characters of letters, digits or underscores")
+	}
+	if n := len(password); n < minPasswordLength || n > maxPasswordLength {
+		errs.Add("password", fmt.Sprintf("must be between %d and %d bytes", minPasswordLength, maxPasswordLength))
+	}
+	return errs.Err()
+}